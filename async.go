@@ -0,0 +1,120 @@
+package maklogger
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// QueueFullPolicy defines what happens when the async queue is full.
+type QueueFullPolicy int
+
+// Policies for a full async queue.
+const (
+	// QueueBlock makes the logging call wait for room in the queue (the default).
+	QueueBlock QueueFullPolicy = iota
+	// QueueDrop discards the record instead of waiting.
+	QueueDrop
+)
+
+// asyncRecord is a rendered record waiting to be written by the async worker.
+// Records with fn set are barriers that run fn on the worker instead.
+type asyncRecord struct {
+	logger *MakLogger
+	level  Level
+	out    io.Writer
+	data   *bytes.Buffer
+	styled []rendering
+	fn     func()
+}
+
+// asyncWriter writes queued records in order from a single goroutine.
+type asyncWriter struct {
+	queue  chan asyncRecord
+	done   chan struct{}
+	policy QueueFullPolicy
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// SetAsync enables asynchronous logging: records are formatted by the caller
+// and queued, up to bufferSize records, for a background goroutine that writes
+// them in order. The policy selects whether a full queue blocks or drops records.
+// A non-positive bufferSize disables async logging after draining the queue.
+// Close and Flush wait until all queued records are written.
+func (mk *MakLogger) SetAsync(bufferSize int, policy QueueFullPolicy) {
+	if mk.async != nil {
+		mk.async.close()
+		mk.async = nil
+	}
+	if bufferSize <= 0 {
+		return
+	}
+
+	a := &asyncWriter{
+		queue:  make(chan asyncRecord, bufferSize),
+		done:   make(chan struct{}),
+		policy: policy,
+	}
+	go a.run()
+	mk.async = a
+}
+
+// run writes queued records until the queue is closed.
+func (a *asyncWriter) run() {
+	defer close(a.done)
+	for r := range a.queue {
+		if r.fn != nil {
+			r.fn()
+			continue
+		}
+		r.logger.emit(r)
+		putBuffer(r.data)
+		for _, styled := range r.styled {
+			putBuffer(styled.b)
+		}
+	}
+}
+
+// enqueue queues a record. It returns false if the writer is closed, in which
+// case the caller writes the record itself. Barriers always wait for room.
+func (a *asyncWriter) enqueue(r asyncRecord) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.closed {
+		return false
+	}
+	if a.policy == QueueDrop && r.fn == nil {
+		select {
+		case a.queue <- r:
+		default:
+		}
+		return true
+	}
+	a.queue <- r
+	return true
+}
+
+// flush waits until all records queued before the call are written, then
+// runs fn on the worker.
+func (a *asyncWriter) flush(fn func() error) error {
+	errc := make(chan error, 1)
+	if !a.enqueue(asyncRecord{fn: func() { errc <- fn() }}) {
+		return fn()
+	}
+	return <-errc
+}
+
+// close stops accepting records and waits for the queue to drain.
+// It is safe to call more than once.
+func (a *asyncWriter) close() {
+	a.mu.Lock()
+	if !a.closed {
+		a.closed = true
+		close(a.queue)
+	}
+	a.mu.Unlock()
+	<-a.done
+}
@@ -1,6 +1,10 @@
 package maklogger
 
-import "fmt"
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
 
 // Color represents an ANSI color code.
 type Color string
@@ -16,6 +20,11 @@ const (
 	LevelCritical
 	LevelError
 	LevelWarn
+	LevelFatal
+	LevelPanic
+
+	// LevelOff is not a level of records; SetLevel(LevelOff) drops all of them.
+	LevelOff
 )
 
 // ANSI color codes for text formatting.
@@ -31,8 +40,8 @@ const (
 	Strikethrough Color = "\033[9m"
 
 	BoldWhite Color = "\033[1;97m"
-	Gray      Color = "\033[90m"   // Gray color for JSON fields
-	DarkGray  Color = "\033[2;37m" // Dark gray
+	Gray      Color = "\033[38;5;245m" // Mid gray from the 256-color palette, the default JSON fields color
+	DarkGray  Color = "\033[2;37m"     // Dimmed white, darker than Gray
 
 	// Text colors
 	Black   Color = "\033[30m"
@@ -75,12 +84,60 @@ const (
 	BgBrightWhite   Color = "\033[107m"
 )
 
+// RGB returns a 24-bit truecolor foreground color.
+func RGB(r, g, b uint8) Color {
+	return Color("\033[38;2;" + rgbParams(r, g, b) + "m")
+}
+
+// BgRGB returns a 24-bit truecolor background color.
+func BgRGB(r, g, b uint8) Color {
+	return Color("\033[48;2;" + rgbParams(r, g, b) + "m")
+}
+
+// Color256 returns a foreground color from the 256-color palette.
+func Color256(n uint8) Color {
+	return Color("\033[38;5;" + strconv.Itoa(int(n)) + "m")
+}
+
+// BgColor256 returns a background color from the 256-color palette.
+func BgColor256(n uint8) Color {
+	return Color("\033[48;5;" + strconv.Itoa(int(n)) + "m")
+}
+
+// rgbParams formats an RGB triple as semicolon-separated escape parameters.
+func rgbParams(r, g, b uint8) string {
+	return strconv.Itoa(int(r)) + ";" + strconv.Itoa(int(g)) + ";" + strconv.Itoa(int(b))
+}
+
 // Colorize applies ANSI color codes to text with optional background color.
 func Colorize(text string, fg Color, bg ...Color) string {
 	if len(bg) > 0 {
-		return fmt.Sprintf("%s%s%s%s", fg, bg[0], text, Reset)
+		return string(fg) + string(bg[0]) + text + string(Reset)
 	}
-	return fmt.Sprintf("%s%s%s", fg, text, Reset)
+	return string(fg) + text + string(Reset)
+}
+
+// ColorizeMulti applies several ANSI attributes to text, e.g. Bold, Underline
+// and a color, followed by a single Reset. Without attributes text is
+// returned unchanged.
+func ColorizeMulti(text string, attrs ...Color) string {
+	if len(attrs) == 0 {
+		return text
+	}
+
+	size := len(text) + len(Reset)
+	for _, attr := range attrs {
+		size += len(attr)
+	}
+
+	var b strings.Builder
+	b.Grow(size)
+	for _, attr := range attrs {
+		b.WriteString(string(attr))
+	}
+	b.WriteString(text)
+	b.WriteString(string(Reset))
+	return b.String()
 }
 
 // ColorizeIfEnabled applies colors only if they are enabled.
@@ -91,3 +148,31 @@ func ColorizeIfEnabled(text string, enabled bool, fg Color, bg ...Color) string
 	}
 	return Colorize(text, fg, bg...)
 }
+
+// Hyperlink wraps text in an OSC 8 escape sequence, making it a clickable link
+// to url in terminals that support it. Other terminals show the plain text.
+func Hyperlink(text, url string) string {
+	return "\033]8;;" + url + "\033\\" + text + "\033]8;;\033\\"
+}
+
+// HyperlinkIfEnabled returns a hyperlink only if enabled is set, and the plain
+// text otherwise, e.g. with enabled set to the logger's ColorsEnabled.
+func HyperlinkIfEnabled(text, url string, enabled bool) string {
+	if !enabled {
+		return text
+	}
+	return Hyperlink(text, url)
+}
+
+// escapeSequence matches ANSI CSI sequences, such as SGR color codes, and OSC
+// sequences, such as hyperlinks, terminated by BEL or ST.
+var escapeSequence = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)`)
+
+// StripColors removes ANSI color codes and OSC sequences such as hyperlinks
+// from s, e.g. to write captured colored output to a plain file.
+func StripColors(s string) string {
+	if !strings.Contains(s, "\x1b") {
+		return s
+	}
+	return escapeSequence.ReplaceAllString(s, "")
+}
@@ -0,0 +1,87 @@
+package maklogger
+
+import "context"
+
+// SetContextExtractor registers a function that derives fields, such as trace
+// IDs, from the context passed to the *Context logging methods. The fields are
+// added before the fields of the call. Passing nil removes the extractor, in
+// which case the context is ignored.
+func (mk *MakLogger) SetContextExtractor(fn func(context.Context) []Field) {
+	mk.contextExtractor = fn
+}
+
+// contextFields prepends the fields extracted from ctx to fields.
+func (mk *MakLogger) contextFields(ctx context.Context, fields []Field) []Field {
+	if mk.contextExtractor == nil || ctx == nil {
+		return fields
+	}
+	extra := mk.contextExtractor(ctx)
+	if len(extra) == 0 {
+		return fields
+	}
+	return append(append(make([]Field, 0, len(extra)+len(fields)), extra...), fields...)
+}
+
+// logContext is like log, adding the fields extracted from ctx. It must be
+// called directly by the exported methods so the caller depth matches log.
+func (mk *MakLogger) logContext(ctx context.Context, level Level, msg string, fields []Field) {
+	if !mk.enabled(level) {
+		return
+	}
+
+	fields = mk.contextFields(ctx, fields)
+	if mk.stackTraceEnabled(level) {
+		fields = appendStack(fields, captureStack(2+mk.callerSkip))
+	}
+
+	r := Record{Time: mk.now(), Level: level, Message: msg, Fields: fields}
+	if !mk.callerDisabled {
+		r.File, r.Line, r.Function = getCallerInfo(2 + mk.callerSkip)
+	}
+	mk.write(r)
+}
+
+// InfoContext logs an informational message with fields extracted from ctx.
+func (mk *MakLogger) InfoContext(ctx context.Context, msg string, fields ...Field) {
+	mk.logContext(ctx, LevelInfo, msg, fields)
+}
+
+// WarnContext logs a warning message with fields extracted from ctx.
+func (mk *MakLogger) WarnContext(ctx context.Context, msg string, fields ...Field) {
+	mk.logContext(ctx, LevelWarn, msg, fields)
+}
+
+// ErrorContext logs an error message with fields extracted from ctx.
+func (mk *MakLogger) ErrorContext(ctx context.Context, msg string, fields ...Field) {
+	mk.logContext(ctx, LevelError, msg, fields)
+}
+
+// SuccessContext logs a success message with fields extracted from ctx.
+func (mk *MakLogger) SuccessContext(ctx context.Context, msg string, fields ...Field) {
+	mk.logContext(ctx, LevelSuccess, msg, fields)
+}
+
+// DebugContext logs a debug message with fields extracted from ctx.
+func (mk *MakLogger) DebugContext(ctx context.Context, msg string, fields ...Field) {
+	mk.logContext(ctx, LevelDebug, msg, fields)
+}
+
+// CriticalContext logs a critical message with fields extracted from ctx.
+func (mk *MakLogger) CriticalContext(ctx context.Context, msg string, fields ...Field) {
+	mk.logContext(ctx, LevelCritical, msg, fields)
+}
+
+// FatalContext logs a fatal message with fields extracted from ctx, flushes
+// the output and exits with the fatal exit code.
+func (mk *MakLogger) FatalContext(ctx context.Context, msg string, fields ...Field) {
+	mk.logContext(ctx, LevelFatal, msg, fields)
+	mk.exit(mk.fatalExitCode)
+}
+
+// PanicContext logs a panic message with fields extracted from ctx, flushes
+// the output and then panics with msg.
+func (mk *MakLogger) PanicContext(ctx context.Context, msg string, fields ...Field) {
+	mk.logContext(ctx, LevelPanic, msg, fields)
+	mk.Flush()
+	panic(msg)
+}
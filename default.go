@@ -0,0 +1,117 @@
+package maklogger
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// defaultLogger is the logger used by the package-level functions.
+var defaultLogger atomic.Pointer[MakLogger]
+
+func init() {
+	defaultLogger.Store(NewLogger())
+}
+
+// Default returns the logger used by the package-level logging functions.
+func Default() *MakLogger {
+	return defaultLogger.Load()
+}
+
+// SetDefault replaces the logger used by the package-level logging functions.
+// A nil logger is ignored.
+func SetDefault(logger *MakLogger) {
+	if logger != nil {
+		defaultLogger.Store(logger)
+	}
+}
+
+// Info logs an informational message with the default logger.
+func Info(msg string, fields ...Field) {
+	Default().log(LevelInfo, msg, fields...)
+}
+
+// Warn logs a warning message with the default logger.
+func Warn(msg string, fields ...Field) {
+	Default().log(LevelWarn, msg, fields...)
+}
+
+// Error logs an error message with the default logger.
+func Error(msg string, fields ...Field) {
+	Default().log(LevelError, msg, fields...)
+}
+
+// Success logs a success message with the default logger.
+func Success(msg string, fields ...Field) {
+	Default().log(LevelSuccess, msg, fields...)
+}
+
+// Debug logs a debug message with the default logger.
+func Debug(msg string, fields ...Field) {
+	Default().log(LevelDebug, msg, fields...)
+}
+
+// Critical logs a critical message with the default logger.
+func Critical(msg string, fields ...Field) {
+	Default().log(LevelCritical, msg, fields...)
+}
+
+// Fatal logs a fatal message with the default logger and exits with its
+// fatal exit code.
+func Fatal(msg string, fields ...Field) {
+	logger := Default()
+	logger.log(LevelFatal, msg, fields...)
+	logger.exit(logger.fatalExitCode)
+}
+
+// FatalCode logs a fatal message with the default logger and exits with code.
+func FatalCode(code int, msg string, fields ...Field) {
+	logger := Default()
+	logger.log(LevelFatal, msg, fields...)
+	logger.exit(code)
+}
+
+// Panic logs a panic message with the default logger and then panics with msg.
+func Panic(msg string, fields ...Field) {
+	logger := Default()
+	logger.log(LevelPanic, msg, fields...)
+	logger.Flush()
+	panic(msg)
+}
+
+// Infof logs a formatted informational message with the default logger.
+func Infof(format string, args ...any) {
+	Default().log(LevelInfo, fmt.Sprintf(format, args...))
+}
+
+// Warnf logs a formatted warning message with the default logger.
+func Warnf(format string, args ...any) {
+	Default().log(LevelWarn, fmt.Sprintf(format, args...))
+}
+
+// Errorf logs a formatted error message with the default logger.
+func Errorf(format string, args ...any) {
+	Default().log(LevelError, fmt.Sprintf(format, args...))
+}
+
+// Successf logs a formatted success message with the default logger.
+func Successf(format string, args ...any) {
+	Default().log(LevelSuccess, fmt.Sprintf(format, args...))
+}
+
+// Debugf logs a formatted debug message with the default logger.
+func Debugf(format string, args ...any) {
+	Default().log(LevelDebug, fmt.Sprintf(format, args...))
+}
+
+// Criticalf logs a formatted critical message with the default logger.
+func Criticalf(format string, args ...any) {
+	Default().log(LevelCritical, fmt.Sprintf(format, args...))
+}
+
+// Fatalf logs a formatted fatal message with the default logger and exits
+// with its fatal exit code.
+func Fatalf(format string, args ...any) {
+	logger := Default()
+	logger.log(LevelFatal, fmt.Sprintf(format, args...))
+	logger.exit(logger.fatalExitCode)
+}
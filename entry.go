@@ -0,0 +1,101 @@
+package maklogger
+
+// Entry builds the fields of a record with chained setters and logs it with
+// one of its level methods:
+//
+//	logger.Entry().Str("user", "bob").Int("id", 5).Info("logged in")
+//
+// An Entry is meant for a single chain and is not safe for concurrent use.
+type Entry struct {
+	logger *MakLogger
+	fields []Field
+}
+
+// Entry returns a new entry logging through the logger.
+func (mk *MakLogger) Entry() *Entry {
+	return &Entry{logger: mk}
+}
+
+// Fields adds fields to the entry.
+func (e *Entry) Fields(fields ...Field) *Entry {
+	e.fields = append(e.fields, fields...)
+	return e
+}
+
+// Any adds a field with any value to the entry.
+func (e *Entry) Any(key string, val any) *Entry {
+	return e.Fields(Field{Key: key, Value: val})
+}
+
+// Str adds a field with a string value to the entry.
+func (e *Entry) Str(key, val string) *Entry {
+	return e.Fields(String(key, val))
+}
+
+// Int adds a field with an int value to the entry.
+func (e *Entry) Int(key string, val int) *Entry {
+	return e.Fields(Int(key, val))
+}
+
+// Bool adds a field with a bool value to the entry.
+func (e *Entry) Bool(key string, val bool) *Entry {
+	return e.Fields(Bool(key, val))
+}
+
+// Float64 adds a field with a float64 value to the entry.
+func (e *Entry) Float64(key string, val float64) *Entry {
+	return e.Fields(Float64(key, val))
+}
+
+// Err adds an error field under the "error" key to the entry.
+func (e *Entry) Err(err error) *Entry {
+	return e.Fields(Err(err))
+}
+
+// The level methods call log directly, so the caller depth matches the
+// level methods of MakLogger.
+
+// Info logs an informational message with the entry's fields.
+func (e *Entry) Info(msg string) {
+	e.logger.log(LevelInfo, msg, e.fields...)
+}
+
+// Success logs a success message with the entry's fields.
+func (e *Entry) Success(msg string) {
+	e.logger.log(LevelSuccess, msg, e.fields...)
+}
+
+// Debug logs a debug message with the entry's fields.
+func (e *Entry) Debug(msg string) {
+	e.logger.log(LevelDebug, msg, e.fields...)
+}
+
+// Warn logs a warning message with the entry's fields.
+func (e *Entry) Warn(msg string) {
+	e.logger.log(LevelWarn, msg, e.fields...)
+}
+
+// Error logs an error message with the entry's fields.
+func (e *Entry) Error(msg string) {
+	e.logger.log(LevelError, msg, e.fields...)
+}
+
+// Critical logs a critical message with the entry's fields.
+func (e *Entry) Critical(msg string) {
+	e.logger.log(LevelCritical, msg, e.fields...)
+}
+
+// Fatal logs a fatal message with the entry's fields, flushes pending output
+// and exits the process with the fatal exit code.
+func (e *Entry) Fatal(msg string) {
+	e.logger.log(LevelFatal, msg, e.fields...)
+	e.logger.exit(e.logger.fatalExitCode)
+}
+
+// Panic logs a panic message with the entry's fields, flushes pending output
+// and then panics with msg.
+func (e *Entry) Panic(msg string) {
+	e.logger.log(LevelPanic, msg, e.fields...)
+	e.logger.Flush()
+	panic(msg)
+}
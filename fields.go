@@ -0,0 +1,402 @@
+package maklogger
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// String returns a field with a string value.
+func String(key, val string) Field {
+	return Field{Key: key, Value: val}
+}
+
+// Int returns a field with an int value.
+func Int(key string, val int) Field {
+	return Field{Key: key, Value: val}
+}
+
+// Bool returns a field with a bool value.
+func Bool(key string, val bool) Field {
+	return Field{Key: key, Value: val}
+}
+
+// Float64 returns a field with a float64 value.
+func Float64(key string, val float64) Field {
+	return Field{Key: key, Value: val}
+}
+
+// Err returns a field with the given error under the "error" key.
+func Err(err error) Field {
+	return Field{Key: "error", Value: err}
+}
+
+// RawJSON returns a field whose value is embedded as-is in the output instead
+// of being encoded as a JSON string. If data is not valid JSON, it is logged
+// as a plain string.
+func RawJSON(key string, data []byte) Field {
+	if !json.Valid(data) {
+		return Field{Key: key, Value: string(data)}
+	}
+	return Field{Key: key, Value: json.RawMessage(append([]byte(nil), data...))}
+}
+
+// Group returns a field whose value is a nested object holding the given
+// fields under key, in the order they were passed, e.g. an "http" object
+// with method, status and path. The nested fields are rendered, redacted and
+// deduplicated like top-level fields. The value of the field is the []Field
+// slice; any []Field value is rendered as a nested object.
+func Group(key string, fields ...Field) Field {
+	return Field{Key: key, Value: fields}
+}
+
+// jsonObject is a rendered object that keeps the order of its entries.
+type jsonObject []jsonEntry
+
+// MarshalJSON encodes the entries as a JSON object in order.
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	writeJSONObject(&b, o)
+	return b.Bytes(), nil
+}
+
+// lazyValue is a field value computed only when the record is rendered.
+type lazyValue func() any
+
+// Lazy returns a field whose value is computed by fn only when the record is
+// actually emitted. Records filtered out by level or sampling never call fn.
+func Lazy(key string, fn func() any) Field {
+	return Field{Key: key, Value: lazyValue(fn)}
+}
+
+// resolveLazy returns fields with lazy values, also those nested in groups,
+// replaced by their results, so hooks see the values and a record rendered
+// more than once, e.g. for sinks with different color settings, calls each
+// function once and shows the same value everywhere. Lazy values and groups
+// of redacted keys are replaced with the redacted placeholder and never
+// computed. Fields is returned as-is if it holds no lazy values or groups.
+func (mk *MakLogger) resolveLazy(fields []Field) []Field {
+	var resolved []Field
+	for i, field := range fields {
+		var value any
+		switch v := field.Value.(type) {
+		case lazyValue:
+			if mk.redacted(field.Key) {
+				value = redactedValue
+			} else if v != nil {
+				value = v()
+			}
+		case []Field:
+			if mk.redacted(field.Key) {
+				value = redactedValue
+			} else {
+				value = mk.resolveLazy(v)
+			}
+		default:
+			continue
+		}
+
+		if resolved == nil {
+			resolved = append(make([]Field, 0, len(fields)), fields...)
+		}
+		resolved[i].Value = value
+	}
+	if resolved == nil {
+		return fields
+	}
+	return resolved
+}
+
+// renderValue converts a field value into a form that can be serialized.
+// Values that encoding/json cannot marshal, such as channels, are replaced
+// with a descriptive string. Errors are rendered with their cause chain,
+// as an object in structured output and as a single string in text output.
+// Durations are rendered with their String method unless raw durations are set,
+// and times like the record timestamp.
+func (mk *MakLogger) renderValue(value any, structured bool) any {
+	if lazy, ok := value.(lazyValue); ok {
+		if lazy == nil {
+			return nil
+		}
+		value = lazy()
+	}
+
+	if value == nil {
+		return nil
+	}
+
+	if group, ok := value.([]Field); ok {
+		return jsonObject(mk.fieldEntries(group, structured))
+	}
+
+	// Raw JSON is embedded as-is, like byte slices holding a JSON object or
+	// array in the default style; invalid raw JSON is logged as a string
+	if raw, ok := value.(json.RawMessage); ok && raw != nil && !json.Valid(raw) {
+		return string(raw)
+	}
+	if data, ok := value.([]byte); ok {
+		switch mk.byteSliceStyle {
+		case ByteSliceString:
+			return string(data)
+		case ByteSliceHex:
+			return hex.EncodeToString(data)
+		}
+		if isJSONDocument(data) {
+			return json.RawMessage(data)
+		}
+	}
+
+	if err, ok := value.(error); ok {
+		return renderError(err, structured || mk.errorCauses)
+	}
+
+	if d, ok := value.(time.Duration); ok && !mk.rawDurations {
+		return d.String()
+	}
+
+	if t, ok := value.(time.Time); ok {
+		return mk.formatTime(t, structured)
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Chan:
+		return fmt.Sprintf("chan(len=%d, cap=%d)", rv.Len(), rv.Cap())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if s, ok := value.(fmt.Stringer); ok && mk.enumStrings {
+			return fmt.Sprintf("%s(%d)", s.String(), rv.Int())
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if s, ok := value.(fmt.Stringer); ok && mk.enumStrings {
+			return fmt.Sprintf("%s(%d)", s.String(), rv.Uint())
+		}
+	}
+
+	return value
+}
+
+// isJSONDocument reports whether data is a valid JSON object or array.
+func isJSONDocument(data []byte) bool {
+	data = bytes.TrimSpace(data)
+	return len(data) > 0 && (data[0] == '{' || data[0] == '[') && json.Valid(data)
+}
+
+// renderError renders an error together with the messages of the errors it
+// wraps, as an object with a causes array when expand is set.
+func renderError(err error, expand bool) any {
+	var causes []string
+	for cause := errors.Unwrap(err); cause != nil; cause = errors.Unwrap(cause) {
+		causes = append(causes, cause.Error())
+	}
+
+	if len(causes) == 0 {
+		return err.Error()
+	}
+
+	if expand {
+		return map[string]any{
+			"message": err.Error(),
+			"causes":  causes,
+		}
+	}
+
+	return err.Error() + " caused by: " + strings.Join(causes, " caused by: ")
+}
+
+// SetErrorCauses sets whether error field values that wrap other errors are
+// rendered in text output as an object with the error message and a causes
+// array, as in structured output. By default the chain is joined into a
+// single string.
+func (mk *MakLogger) SetErrorCauses(enabled bool) {
+	mk.errorCauses = enabled
+}
+
+// ByteSliceStyle defines how []byte field values are rendered.
+type ByteSliceStyle int
+
+// Styles of []byte field values.
+const (
+	// ByteSliceBase64 renders byte slices as base64 strings, or embeds them
+	// as-is if they hold a JSON object or array (the default).
+	ByteSliceBase64 ByteSliceStyle = iota
+	// ByteSliceString renders byte slices as strings, replacing invalid UTF-8.
+	ByteSliceString
+	// ByteSliceHex renders byte slices as lowercase hex strings.
+	ByteSliceHex
+)
+
+// SetByteSliceStyle sets how []byte field values are rendered. Byte slices
+// holding a JSON object or array are only embedded as-is in the default
+// style; the other styles render every byte slice alike.
+func (mk *MakLogger) SetByteSliceStyle(style ByteSliceStyle) {
+	mk.byteSliceStyle = style
+}
+
+// ByteSliceStyle returns how []byte field values are rendered.
+func (mk *MakLogger) ByteSliceStyle() ByteSliceStyle {
+	return mk.byteSliceStyle
+}
+
+// formatTime formats a time field value like the record timestamp: with the
+// logger's time format in text output and RFC 3339 in structured output,
+// converted to UTC if that is configured.
+func (mk *MakLogger) formatTime(t time.Time, structured bool) string {
+	if mk.utc {
+		t = t.UTC()
+	}
+	if structured || mk.timeFormat == "" {
+		return t.Format(time.RFC3339Nano)
+	}
+	return t.Format(mk.timeFormat)
+}
+
+// SetRawDurations sets whether time.Duration field values are rendered as
+// raw nanosecond integers. By default they are rendered with their String
+// method, e.g. "1.5s".
+func (mk *MakLogger) SetRawDurations(enabled bool) {
+	mk.rawDurations = enabled
+}
+
+// SetEnumStrings sets whether integer field values implementing fmt.Stringer
+// are rendered with both their name and numeric value, e.g. "Active(1)".
+// By default such values are rendered as plain numbers.
+func (mk *MakLogger) SetEnumStrings(enabled bool) {
+	mk.enumStrings = enabled
+}
+
+// SetNumberGrouping sets whether numeric field values are rendered with
+// thousands separators, e.g. "1,234,567", in text output.
+// Structured formats such as JSON always keep raw numbers. Grouped numbers
+// are strings in the fields block, but SetTypedFieldColors still colors them
+// as numbers.
+func (mk *MakLogger) SetNumberGrouping(enabled bool) {
+	mk.numberGrouping = enabled
+}
+
+// SetRedactKeys sets the field keys whose values are replaced with "***" in
+// the output. Keys are matched case-insensitively against top-level fields
+// and the fields nested with Group; map and struct values are not scanned.
+// Calling it with no keys disables redaction.
+func (mk *MakLogger) SetRedactKeys(keys ...string) {
+	if len(keys) == 0 {
+		mk.redactKeys = nil
+		return
+	}
+	redactKeys := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		redactKeys[strings.ToLower(key)] = struct{}{}
+	}
+	mk.redactKeys = redactKeys
+}
+
+// redacted reports whether the value of the field with the given key is redacted.
+func (mk *MakLogger) redacted(key string) bool {
+	if mk.redactKeys == nil {
+		return false
+	}
+	_, ok := mk.redactKeys[strings.ToLower(key)]
+	return ok
+}
+
+// groupNumber returns numeric values as strings with thousands separators.
+// Other values are returned unchanged.
+func groupNumber(value any) any {
+	if value == nil {
+		return nil
+	}
+
+	var s string
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s = strconv.FormatInt(rv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s = strconv.FormatUint(rv.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		s = strconv.FormatFloat(rv.Float(), 'f', -1, rv.Type().Bits())
+	default:
+		return value
+	}
+
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+
+	fraction := ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		s, fraction = s[:i], s[i:]
+	}
+
+	var b strings.Builder
+	for i, digit := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(digit)
+	}
+
+	return sign + b.String() + fraction
+}
+
+// redactedValue replaces sensitive values in log output.
+const redactedValue = "***"
+
+// requestHeaders lists the request headers included by Request.
+var requestHeaders = []string{
+	"User-Agent",
+	"Content-Type",
+	"Referer",
+	"X-Forwarded-For",
+	"X-Request-Id",
+	"Authorization",
+	"Cookie",
+}
+
+// sensitiveHeaders lists the request headers whose values are masked by Request.
+var sensitiveHeaders = map[string]bool{
+	"Authorization": true,
+	"Cookie":        true,
+}
+
+// Request returns a field describing an HTTP request under the "request" key.
+// It includes the method, path, query parameters as an object of value lists,
+// remote address and a selection of headers. Authorization and Cookie headers are masked, as are the values of
+// any query parameters listed in redactParams.
+func Request(r *http.Request, redactParams ...string) Field {
+	query := r.URL.Query()
+	for _, param := range redactParams {
+		if values, ok := query[param]; ok {
+			for i := range values {
+				values[i] = redactedValue
+			}
+		}
+	}
+
+	headers := make(map[string]string)
+	for _, name := range requestHeaders {
+		value := r.Header.Get(name)
+		if value == "" {
+			continue
+		}
+		if sensitiveHeaders[name] {
+			value = redactedValue
+		}
+		headers[name] = value
+	}
+
+	return Field{Key: "request", Value: map[string]any{
+		"method":      r.Method,
+		"path":        r.URL.Path,
+		"query":       map[string][]string(query),
+		"remote_addr": r.RemoteAddr,
+		"headers":     headers,
+	}}
+}
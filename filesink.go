@@ -0,0 +1,123 @@
+package maklogger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// fileSink is a log file that is rotated when it exceeds a size limit.
+type fileSink struct {
+	path       string
+	maxBytes   int64
+	maxBackups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewFileSink opens the log file at path for appending and returns a writer
+// that rotates it once writing a record would grow it beyond maxBytes:
+// app.log is renamed to app.log.1, app.log.1 to app.log.2 and so on, keeping
+// at most maxBackups old files. A non-positive maxBytes disables rotation.
+// Since the sink is not a terminal, colors are disabled automatically when it
+// is passed to SetOutput.
+func NewFileSink(path string, maxBytes int64, maxBackups int) (io.WriteCloser, error) {
+	s := &fileSink{path: path, maxBytes: maxBytes, maxBackups: maxBackups}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// open opens the log file and records its current size.
+func (s *fileSink) open() error {
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("maklogger: open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("maklogger: open log file: %w", err)
+	}
+	s.file = file
+	s.size = info.Size()
+	return nil
+}
+
+// Write writes p to the log file, rotating it first if p does not fit.
+// A record larger than maxBytes is written to an empty file rather than split.
+func (s *fileSink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	if s.maxBytes > 0 && s.size > 0 && s.size+int64(len(p)) > s.maxBytes {
+		if err := s.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := s.file.Write(p)
+	s.size += int64(n)
+	return n, err
+}
+
+// rotate shifts the backups, moves the current file to the first backup
+// and opens a new file.
+func (s *fileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return fmt.Errorf("maklogger: rotate log file: %w", err)
+	}
+	s.file = nil
+
+	if s.maxBackups > 0 {
+		os.Remove(s.backupPath(s.maxBackups))
+		for i := s.maxBackups - 1; i >= 1; i-- {
+			os.Rename(s.backupPath(i), s.backupPath(i+1))
+		}
+		if err := os.Rename(s.path, s.backupPath(1)); err != nil {
+			return fmt.Errorf("maklogger: rotate log file: %w", err)
+		}
+	} else if err := os.Remove(s.path); err != nil {
+		return fmt.Errorf("maklogger: rotate log file: %w", err)
+	}
+
+	return s.open()
+}
+
+// backupPath returns the path of the backup with the given number.
+func (s *fileSink) backupPath(n int) string {
+	return s.path + "." + strconv.Itoa(n)
+}
+
+// Sync commits the log file to stable storage, so SetFlushEach works with the sink.
+func (s *fileSink) Sync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return os.ErrClosed
+	}
+	return s.file.Sync()
+}
+
+// Close closes the log file. It is safe to call more than once.
+func (s *fileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
@@ -0,0 +1,371 @@
+package maklogger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Format represents the output format of log records.
+type Format int
+
+// Supported output formats.
+const (
+	// FormatText is the default human-readable colored format.
+	FormatText Format = iota
+	// FormatJSON emits each record as a single-line JSON object.
+	FormatJSON
+	// FormatLogfmt emits each record as a single line of key=value pairs.
+	FormatLogfmt
+	// FormatSyslog emits each record as an RFC 5424 syslog line.
+	FormatSyslog
+)
+
+// lineBreakEscaper escapes line breaks in messages of line-oriented output.
+var lineBreakEscaper = strings.NewReplacer("\r", `\r`, "\n", `\n`)
+
+// CollisionPolicy defines what happens when a field key collides with
+// one of the reserved keys used in structured output (time, level, msg, caller,
+// and logger for named loggers).
+type CollisionPolicy int
+
+// Collision policies for reserved keys.
+const (
+	// CollisionPrefix renames the user field to "fields.<key>" (the default).
+	CollisionPrefix CollisionPolicy = iota
+	// CollisionError drops the user field and reports an error via OnError.
+	CollisionError
+	// CollisionUserWins replaces the reserved value with the user field value.
+	CollisionUserWins
+)
+
+// DuplicateKeyPolicy defines what happens when several fields of a record share
+// a key, e.g. a field added by WithFields and one passed to the log call.
+type DuplicateKeyPolicy int
+
+// Policies for duplicate field keys.
+const (
+	// DuplicateKeepLast keeps the position of the first field and the value
+	// of the last one (the default).
+	DuplicateKeepLast DuplicateKeyPolicy = iota
+	// DuplicateKeepFirst keeps the first field and drops the later ones.
+	DuplicateKeepFirst
+	// DuplicateRename keeps every field, appending "#2", "#3" and so on to
+	// the repeated keys.
+	DuplicateRename
+)
+
+// JSONKeys holds the names of the reserved keys in structured output.
+// An empty name selects the default key.
+type JSONKeys struct {
+	Time    string
+	Level   string
+	Message string
+	Caller  string
+	Logger  string
+}
+
+// defaultJSONKeys are the reserved key names used by FormatJSON.
+var defaultJSONKeys = JSONKeys{
+	Time:    "time",
+	Level:   "level",
+	Message: "msg",
+	Caller:  "caller",
+	Logger:  "logger",
+}
+
+// SetFormat sets the output format of log records.
+func (mk *MakLogger) SetFormat(format Format) {
+	mk.format = format
+}
+
+// Format returns the current output format.
+func (mk *MakLogger) Format() Format {
+	return mk.format
+}
+
+// SetCollisionPolicy sets how field keys colliding with reserved keys are handled
+// in structured output formats.
+func (mk *MakLogger) SetCollisionPolicy(policy CollisionPolicy) {
+	mk.collisionPolicy = policy
+}
+
+// CollisionPolicy returns the current reserved key collision policy.
+func (mk *MakLogger) CollisionPolicy() CollisionPolicy {
+	return mk.collisionPolicy
+}
+
+// SetJSONKeys renames the reserved keys used in structured output.
+// Empty names keep the default key.
+func (mk *MakLogger) SetJSONKeys(keys JSONKeys) {
+	mk.jsonKeys = keys
+}
+
+// JSONKeys returns the reserved key names used in structured output.
+func (mk *MakLogger) JSONKeys() JSONKeys {
+	keys := mk.jsonKeys
+	if keys.Time == "" {
+		keys.Time = defaultJSONKeys.Time
+	}
+	if keys.Level == "" {
+		keys.Level = defaultJSONKeys.Level
+	}
+	if keys.Message == "" {
+		keys.Message = defaultJSONKeys.Message
+	}
+	if keys.Caller == "" {
+		keys.Caller = defaultJSONKeys.Caller
+	}
+	if keys.Logger == "" {
+		keys.Logger = defaultJSONKeys.Logger
+	}
+	return keys
+}
+
+// SetTimeKey renames the reserved time key.
+func (mk *MakLogger) SetTimeKey(key string) {
+	mk.jsonKeys.Time = key
+}
+
+// SetLevelKey renames the reserved level key.
+func (mk *MakLogger) SetLevelKey(key string) {
+	mk.jsonKeys.Level = key
+}
+
+// SetMessageKey renames the reserved message key.
+func (mk *MakLogger) SetMessageKey(key string) {
+	mk.jsonKeys.Message = key
+}
+
+// SetCallerKey renames the reserved caller key.
+func (mk *MakLogger) SetCallerKey(key string) {
+	mk.jsonKeys.Caller = key
+}
+
+// levelName returns the lowercase name of a level used in structured output.
+func levelName(level Level) string {
+	switch level {
+	case LevelInfo:
+		return "info"
+	case LevelSuccess:
+		return "success"
+	case LevelDebug:
+		return "debug"
+	case LevelCritical:
+		return "critical"
+	case LevelError:
+		return "error"
+	case LevelWarn:
+		return "warning"
+	case LevelFatal:
+		return "fatal"
+	case LevelPanic:
+		return "panic"
+	}
+
+	return "undefined"
+}
+
+// jsonEntry is a single key-value pair of a structured record.
+type jsonEntry struct {
+	key   string
+	value any
+}
+
+// SetDuplicateKeyPolicy sets how fields sharing a key within a record are handled.
+func (mk *MakLogger) SetDuplicateKeyPolicy(policy DuplicateKeyPolicy) {
+	mk.duplicateKeyPolicy = policy
+}
+
+// DuplicateKeyPolicy returns the current duplicate key policy.
+func (mk *MakLogger) DuplicateKeyPolicy() DuplicateKeyPolicy {
+	return mk.duplicateKeyPolicy
+}
+
+// SetSortFields sets whether fields are written sorted alphabetically by key
+// instead of in the order they were passed, for diff-friendly output.
+// In structured formats the reserved keys stay first.
+func (mk *MakLogger) SetSortFields(enabled bool) {
+	mk.sortFields = enabled
+}
+
+// fieldEntries converts fields into entries ready for serialization, in the
+// order they were passed or sorted by key if that is configured. Repeated
+// keys are handled according to the duplicate key policy. Values of redacted
+// keys are replaced. Structured selects the value rendering of JSON and
+// logfmt output.
+func (mk *MakLogger) fieldEntries(fields []Field, structured bool) []jsonEntry {
+	entries := make([]jsonEntry, 0, len(fields))
+	index := make(map[string]int, len(fields))
+	for _, field := range fields {
+		key := field.Key
+		if i, ok := index[key]; ok {
+			switch mk.duplicateKeyPolicy {
+			case DuplicateKeepFirst:
+				continue
+			case DuplicateRename:
+				key = renameDuplicate(index, key)
+			default:
+				entries[i].value = mk.fieldValue(field, structured)
+				continue
+			}
+		}
+		index[key] = len(entries)
+		entries = append(entries, jsonEntry{key, mk.fieldValue(field, structured)})
+	}
+	if mk.sortFields {
+		sort.SliceStable(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+	}
+	return entries
+}
+
+// fieldValue returns the rendered value of a field, or the redaction mask.
+// Long string values are truncated if that is configured.
+func (mk *MakLogger) fieldValue(field Field, structured bool) any {
+	if mk.redacted(field.Key) {
+		return redactedValue
+	}
+	value := mk.renderValue(field.Value, structured)
+	if s, ok := value.(string); ok && mk.maxFieldLength > 0 && len(s) > mk.maxFieldLength {
+		return truncateValue(s, mk.maxFieldLength)
+	}
+	return value
+}
+
+// truncateValue cuts s to at most n bytes without splitting a UTF-8 character
+// and appends a notice with the original length.
+func truncateValue(s string, n int) string {
+	cut := n
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "…(truncated, " + strconv.Itoa(len(s)) + " bytes)"
+}
+
+// renameDuplicate returns key with the first "#N" suffix, starting at 2,
+// that is not in index yet.
+func renameDuplicate(index map[string]int, key string) string {
+	for n := 2; ; n++ {
+		renamed := key + "#" + strconv.Itoa(n)
+		if _, ok := index[renamed]; !ok {
+			return renamed
+		}
+	}
+}
+
+// structuredEntries returns the reserved entries of a record followed by its
+// fields, applying the reserved key collision policy.
+func (mk *MakLogger) structuredEntries(now time.Time, level Level, file string, line int, msg string, fields []Field) []jsonEntry {
+	keys := mk.JSONKeys()
+	entries := []jsonEntry{
+		{keys.Time, now.Format(time.RFC3339Nano)},
+		{keys.Level, levelName(level)},
+	}
+	if mk.name != "" {
+		entries = append(entries, jsonEntry{keys.Logger, mk.name})
+	}
+	entries = append(entries, jsonEntry{keys.Message, msg})
+	if file != "" {
+		entries = append(entries, jsonEntry{keys.Caller, mk.callerFile(file) + ":" + strconv.Itoa(line)})
+	}
+	reservedCount := len(entries)
+
+	for _, field := range mk.fieldEntries(fields, true) {
+		reserved := -1
+		for i := 0; i < reservedCount; i++ {
+			if entries[i].key == field.key {
+				reserved = i
+				break
+			}
+		}
+
+		if reserved < 0 {
+			entries = append(entries, field)
+			continue
+		}
+
+		switch mk.collisionPolicy {
+		case CollisionError:
+			if mk.onError != nil {
+				mk.onError(fmt.Errorf("maklogger: field %q collides with a reserved key", field.key))
+			}
+		case CollisionUserWins:
+			entries[reserved].value = field.value
+		default:
+			entries = append(entries, jsonEntry{"fields." + field.key, field.value})
+		}
+	}
+
+	return entries
+}
+
+// renderJSON formats a record as a single-line JSON object.
+func (mk *MakLogger) renderJSON(b *bytes.Buffer, now time.Time, level Level, file string, line int, msg string, fields []Field) {
+	timer := mk.fieldsTimer(fields)
+	writeJSONObject(b, mk.structuredEntries(now, level, file, line, msg, fields))
+	mk.checkSlowFields(timer, len(fields))
+	b.WriteString(mk.lineEnding)
+}
+
+// renderLogfmt formats a record as a single line of logfmt key=value pairs.
+func (mk *MakLogger) renderLogfmt(b *bytes.Buffer, now time.Time, level Level, file string, line int, msg string, fields []Field) {
+	timer := mk.fieldsTimer(fields)
+	for i, entry := range mk.structuredEntries(now, level, file, line, msg, fields) {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(entry.key)
+		b.WriteByte('=')
+		b.WriteString(logfmtValue(entry.value))
+	}
+	mk.checkSlowFields(timer, len(fields))
+	b.WriteString(mk.lineEnding)
+}
+
+// logfmtValue formats a value for logfmt output. Strings are written as-is
+// unless they are empty or contain spaces, quotes, '=' or control characters,
+// in which case they are quoted. Other values are encoded as JSON.
+func logfmtValue(value any) string {
+	s, ok := value.(string)
+	if !ok {
+		if value == nil {
+			return ""
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			encoded = []byte(fmt.Sprintf("failed to marshal field: %v", err))
+		}
+		s = string(encoded)
+	}
+
+	if s == "" || strings.ContainsAny(s, " =\"\\") || strings.IndexFunc(s, unicode.IsControl) >= 0 {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// writeJSONObject writes entries as a compact JSON object, preserving their order.
+// Values that fail to marshal are replaced with a description of the error.
+func writeJSONObject(b *bytes.Buffer, entries []jsonEntry) {
+	b.WriteByte('{')
+	for i, entry := range entries {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(entry.key)
+		b.Write(key)
+		b.WriteByte(':')
+		value, err := json.Marshal(entry.value)
+		if err != nil {
+			value, _ = json.Marshal(fmt.Sprintf("failed to marshal field: %v", err))
+		}
+		b.Write(value)
+	}
+	b.WriteByte('}')
+}
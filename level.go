@@ -0,0 +1,197 @@
+package maklogger
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// levelNames maps lowercase level names to levels for ParseLevel.
+var levelNames = map[string]Level{
+	"info":     LevelInfo,
+	"success":  LevelSuccess,
+	"debug":    LevelDebug,
+	"critical": LevelCritical,
+	"error":    LevelError,
+	"warn":     LevelWarn,
+	"warning":  LevelWarn,
+	"fatal":    LevelFatal,
+	"panic":    LevelPanic,
+	"off":      LevelOff,
+}
+
+var (
+	levelAliasesMu sync.RWMutex
+	levelAliases   = make(map[string]Level)
+)
+
+// RegisterLevelAlias registers an additional name that ParseLevel maps to level.
+// Aliases are case-insensitive and may override the built-in names.
+func RegisterLevelAlias(alias string, level Level) {
+	levelAliasesMu.Lock()
+	defer levelAliasesMu.Unlock()
+	levelAliases[strings.ToLower(alias)] = level
+}
+
+// unregisterLevelAlias removes an alias registered with RegisterLevelAlias.
+// Tests use it to restore the global alias table.
+func unregisterLevelAlias(alias string) {
+	levelAliasesMu.Lock()
+	defer levelAliasesMu.Unlock()
+	delete(levelAliases, strings.ToLower(alias))
+}
+
+// String returns the uppercase name of the level, such as "INFO" or "WARN".
+func (l Level) String() string {
+	switch l {
+	case LevelInfo:
+		return "INFO"
+	case LevelSuccess:
+		return "SUCCESS"
+	case LevelDebug:
+		return "DEBUG"
+	case LevelCritical:
+		return "CRITICAL"
+	case LevelError:
+		return "ERROR"
+	case LevelWarn:
+		return "WARN"
+	case LevelFatal:
+		return "FATAL"
+	case LevelPanic:
+		return "PANIC"
+	case LevelOff:
+		return "OFF"
+	}
+
+	return fmt.Sprintf("Level(%d)", int(l))
+}
+
+// ParseLevel returns the level with the given name. Parsing is case-insensitive
+// and also accepts aliases registered with RegisterLevelAlias.
+func ParseLevel(s string) (Level, error) {
+	name := strings.ToLower(strings.TrimSpace(s))
+
+	levelAliasesMu.RLock()
+	level, ok := levelAliases[name]
+	levelAliasesMu.RUnlock()
+	if ok {
+		return level, nil
+	}
+
+	if level, ok := levelNames[name]; ok {
+		return level, nil
+	}
+
+	return LevelInfo, fmt.Errorf("maklogger: unknown level %q", s)
+}
+
+// severity returns the rank of a level used for filtering, from least
+// to most severe. The Level constants themselves are not ordered by severity.
+func severity(level Level) int {
+	switch level {
+	case LevelDebug:
+		return 0
+	case LevelInfo:
+		return 1
+	case LevelSuccess:
+		return 2
+	case LevelWarn:
+		return 3
+	case LevelError:
+		return 4
+	case LevelCritical:
+		return 5
+	case LevelPanic:
+		return 6
+	case LevelFatal:
+		return 7
+	case LevelOff:
+		return 8
+	}
+
+	return 0
+}
+
+// SetLevel sets the minimum level of messages to log.
+// Messages less severe than the given level are dropped.
+// Severity order is Debug, Info, Success, Warn, Error, Critical, Panic, Fatal.
+// LevelOff drops all messages.
+func (mk *MakLogger) SetLevel(level Level) {
+	mk.level = level
+}
+
+// Level returns the minimum level of messages that are logged.
+func (mk *MakLogger) Level() Level {
+	return mk.level
+}
+
+// DisableLevel turns off messages at the given level regardless of the
+// minimum level, e.g. to drop Debug and Success while keeping Info.
+func (mk *MakLogger) DisableLevel(level Level) {
+	mk.setLevelDisabled(level, true)
+}
+
+// EnableLevel turns messages at the given level back on after DisableLevel.
+// Messages less severe than the minimum level stay filtered.
+func (mk *MakLogger) EnableLevel(level Level) {
+	mk.setLevelDisabled(level, false)
+}
+
+// setLevelDisabled updates the set of disabled levels.
+func (mk *MakLogger) setLevelDisabled(level Level, disabled bool) {
+	if !disabled {
+		delete(mk.disabledLevels, level)
+		return
+	}
+	if mk.disabledLevels == nil {
+		mk.disabledLevels = make(map[Level]bool)
+	}
+	mk.disabledLevels[level] = true
+}
+
+// enabled reports whether a message at the given level should be logged.
+func (mk *MakLogger) enabled(level Level) bool {
+	return level != LevelOff && severity(level) >= severity(mk.level) && !mk.disabledLevels[level]
+}
+
+// SetSilent sets whether the logger writes nothing. Unlike SetLevel(LevelOff),
+// records still pass the level threshold, sampling and rate limits and are
+// passed to hooks, so metrics and alerts built on hooks keep working.
+func (mk *MakLogger) SetSilent(silent bool) {
+	mk.silent = silent
+}
+
+// Silent returns whether the logger writes nothing.
+func (mk *MakLogger) Silent() bool {
+	return mk.silent
+}
+
+// levelCounts counts the records written per level.
+type levelCounts [LevelPanic + 1]atomic.Uint64
+
+// add counts a record at the given level. Unknown levels are not counted, and
+// a nil levelCounts counts nothing.
+func (c *levelCounts) add(level Level) {
+	if c != nil && level >= 0 && int(level) < len(c) {
+		c[level].Add(1)
+	}
+}
+
+// Counts returns the number of records written per level, e.g. to expose
+// them as metrics. Levels without records are omitted. A record is counted
+// once it is written to its output, or to stderr by the fallback; records
+// dropped by level filtering, sampling, rate limiting or a full async queue,
+// records whose write failed and the "suppressed N messages" summaries of
+// the rate limiter are not counted. Loggers derived with WithFields or Named
+// share the counters of their parent.
+func (mk *MakLogger) Counts() map[Level]uint64 {
+	counts := make(map[Level]uint64)
+	for level := range mk.counts {
+		if n := mk.counts[level].Load(); n > 0 {
+			counts[Level(level)] = n
+		}
+	}
+	return counts
+}
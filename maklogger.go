@@ -4,19 +4,96 @@ package maklogger
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"maps"
+	"os"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
-	"syscall"
+	"sync"
+	"sync/atomic"
 	"time"
-	"unsafe"
 )
 
 // MakLogger represents the main logger instance with configurable color support.
 type MakLogger struct {
-	colorsEnabled bool
+	colorsEnabled  bool
+	colorsExplicit bool
+	out            io.Writer
+	buffered       *bufferedWriter
+	levelOutputs   map[Level]io.Writer
+	sinks          []sink
+	level          Level
+	disabledLevels map[Level]bool
+	silent         bool
+	fields         []Field
+	name           string
+	maxRecordBytes int
+	maxFieldLength int
+	timeFormat     string
+	separator      string
+	lineEnding     string
+	utc            bool
+	clock          func() time.Time
+	sampler        *sampler
+	limiter        *rateLimiter
+	flushEach      bool
+	callerDisabled bool
+	callerSkip     int
+	audit          *auditChain
+	async          *asyncWriter
+	writeMu        *sync.Mutex    // shared with derived loggers
+	batch          *[]asyncRecord // collects the records of LogBatch
+	counts         *levelCounts   // shared with derived loggers
+
+	callerFullPath   bool
+	callerTrimPrefix string
+	callerFullFunc   bool
+
+	fieldsBaseIndent     int
+	fieldStyle           FieldStyle
+	fieldTrailingNewline bool
+
+	stackTrace      bool
+	stackTraceLevel Level
+
+	assertLevel   Level
+	assertFatal   bool
+	fatalExitCode int
+
+	enumStrings    bool
+	numberGrouping bool
+	errorCauses    bool
+	rawDurations   bool
+	byteSliceStyle ByteSliceStyle
+	redactKeys     map[string]struct{}
+	sortFields     bool
+
+	trailingResetDisabled bool
+	resetPending          *atomic.Bool // shared with derived loggers
+
+	theme            Theme
+	labelWidth       int
+	iconsDisabled    bool
+	typedFieldColors bool
+
+	format             Format
+	collisionPolicy    CollisionPolicy
+	duplicateKeyPolicy DuplicateKeyPolicy
+	jsonKeys           JSONKeys
+
+	onError             func(error)
+	writeErrorHandler   func(error)
+	fallbackToStderr    bool
+	contextExtractor    func(context.Context) []Field
+	hooks               []func(level Level, msg string, fields []Field)
+	otelEmitter         OTelEmitter
+	slowFieldsThreshold time.Duration
+	lastSlowFieldsWarn  *atomic.Int64 // unix nanoseconds, shared with derived loggers
 }
 
 // Field represents a key-value pair for structured logging.
@@ -26,64 +103,72 @@ type Field struct {
 	Value any
 }
 
-var buf bytes.Buffer
+// DefaultTimeFormat is the default layout of timestamps in text output.
+const DefaultTimeFormat = "2006-01-02 15:04:05.000"
+
+// DefaultSeparator is the default separator between the segments of a text
+// record, such as the timestamp, level, caller and message.
+const DefaultSeparator = " │ "
 
-// NewLogger creates a new MakLogger instance with colors enabled by default.
+// NewLogger creates a new MakLogger instance writing to os.Stdout.
+// Colors are enabled when stdout is a terminal and disabled when it is
+// redirected to a file or pipe, or when the NO_COLOR environment variable is set.
 // On Windows, it automatically enables ANSI color support for CMD.
 // On Unix systems (Linux/macOS), ANSI colors are supported by default.
 func NewLogger() *MakLogger {
-	logger := &MakLogger{colorsEnabled: true}
+	logger := &MakLogger{
+		level:            LevelDebug,
+		timeFormat:       DefaultTimeFormat,
+		separator:        DefaultSeparator,
+		lineEnding:       "\n",
+		fieldsBaseIndent: 2,
+		assertLevel:      LevelCritical,
+		fatalExitCode:    1,
+		resetPending:     new(atomic.Bool),
+		writeMu:          new(sync.Mutex),
+		counts:           new(levelCounts),
 
-	// Enable ANSI colors for Windows CMD
-	if runtime.GOOS == "windows" {
-		logger.enableWindowsANSI()
+		lastSlowFieldsWarn: new(atomic.Int64),
 	}
-	// On Unix systems (Linux/macOS) ANSI colors are supported by default
+	logger.updateLabelWidth()
+	logger.detectColors(os.Stdout)
 
 	return logger
 }
 
-// enableWindowsANSI enables ANSI escape sequence support in Windows CMD.
-func (mk *MakLogger) enableWindowsANSI() {
-	if runtime.GOOS != "windows" {
-		return // Do nothing on non-Windows systems
+// NewTestLogger creates a logger writing to the returned in-memory buffer with
+// colors disabled, so tests of code that logs can assert on its output.
+// Records are written to the buffer under the logger's write lock; read it
+// after the code under test has finished logging.
+func NewTestLogger() (*MakLogger, *bytes.Buffer) {
+	buf := new(bytes.Buffer)
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+	logger.SetOutput(buf)
+	return logger, buf
+}
+
+// detectColors enables colors if w is a terminal, unless colors were
+// configured explicitly with SetColorsEnabled.
+func (mk *MakLogger) detectColors(w io.Writer) {
+	if mk.colorsExplicit {
+		return
 	}
 
-	defer func() {
-		if r := recover(); r != nil {
-			// If we couldn't enable ANSI, disable colors
-			mk.colorsEnabled = false
-		}
-	}()
-
-	// Windows-specific constants
-	const (
-		STD_OUTPUT_HANDLE                  = ^uintptr(10) // -11 as uintptr
-		ENABLE_VIRTUAL_TERMINAL_PROCESSING = 0x0004
-	)
-
-	// Load Windows API functions
-	kernel32 := syscall.NewLazyDLL("kernel32.dll")
-	procGetConsoleMode := kernel32.NewProc("GetConsoleMode")
-	procSetConsoleMode := kernel32.NewProc("SetConsoleMode")
-	procGetStdHandle := kernel32.NewProc("GetStdHandle")
-
-	handle, _, _ := procGetStdHandle.Call(STD_OUTPUT_HANDLE)
-	var mode uint32
-
-	// Get current console mode
-	ret, _, _ := procGetConsoleMode.Call(handle, uintptr(unsafe.Pointer(&mode)))
-	if ret == 0 {
+	// Respect the NO_COLOR convention (https://no-color.org)
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
 		mk.colorsEnabled = false
 		return
 	}
 
-	// Enable virtual terminal
-	mode |= ENABLE_VIRTUAL_TERMINAL_PROCESSING
-	ret, _, _ = procSetConsoleMode.Call(handle, uintptr(mode))
-	if ret == 0 {
-		mk.colorsEnabled = false
+	f, ok := w.(*os.File)
+	mk.colorsEnabled = ok && isTerminal(f)
+
+	// Enable ANSI colors for Windows CMD
+	if mk.colorsEnabled && runtime.GOOS == "windows" {
+		mk.enableWindowsANSI()
 	}
+	// On Unix systems (Linux/macOS) ANSI colors are supported by default
 }
 
 // ColorsEnabled returns whether colors are currently enabled.
@@ -92,85 +177,881 @@ func (mk *MakLogger) ColorsEnabled() bool {
 }
 
 // SetColorsEnabled sets whether colors should be used in log output.
+// An explicit setting overrides terminal detection in NewLogger and SetOutput.
 func (mk *MakLogger) SetColorsEnabled(enabled bool) {
 	mk.colorsEnabled = enabled
+	mk.colorsExplicit = true
+}
+
+// SetOutput sets the destination for log output.
+// Passing nil restores the default, os.Stdout. Records still buffered by
+// SetBufferedOutput are flushed to the previous output first.
+// Unless colors were set explicitly, they are re-evaluated for the new writer.
+func (mk *MakLogger) SetOutput(w io.Writer) {
+	mk.Flush()
+	mk.out = w
+	mk.buffered = nil
+	mk.detectColors(mk.Output())
+}
+
+// Output returns the writer log output is sent to.
+func (mk *MakLogger) Output() io.Writer {
+	if mk.out == nil {
+		return os.Stdout
+	}
+	return mk.out
+}
+
+// SetFieldsBaseIndent sets the number of spaces prepended to every line of the
+// fields block, on top of the JSON indentation. The default is 2; use 0 to
+// print the block flush-left.
+func (mk *MakLogger) SetFieldsBaseIndent(n int) {
+	if n < 0 {
+		n = 0
+	}
+	mk.fieldsBaseIndent = n
+}
+
+// FieldsBaseIndent returns the base indentation of the fields block.
+func (mk *MakLogger) FieldsBaseIndent() int {
+	return mk.fieldsBaseIndent
+}
+
+// FieldStyle selects how fields are laid out in text output.
+type FieldStyle int
+
+// Field layouts for text output.
+const (
+	// FieldStylePretty prints fields as indented JSON below the message (the default).
+	FieldStylePretty FieldStyle = iota
+	// FieldStyleInline prints fields as compact single-line JSON after the message.
+	FieldStyleInline
+)
+
+// SetFieldStyle sets how fields are laid out in text output.
+// Structured formats are not affected.
+func (mk *MakLogger) SetFieldStyle(style FieldStyle) {
+	mk.fieldStyle = style
+}
+
+// FieldStyle returns how fields are laid out in text output.
+func (mk *MakLogger) FieldStyle() FieldStyle {
+	return mk.fieldStyle
+}
+
+// SetFieldTrailingNewline sets whether a blank line follows the fields block
+// of pretty text records, separating them from the next record. By default
+// records with fields are as compact as those without.
+func (mk *MakLogger) SetFieldTrailingNewline(enabled bool) {
+	mk.fieldTrailingNewline = enabled
+}
+
+// SetIconsEnabled sets whether emoji icons are printed in text output.
+// When disabled, the timestamp, level, caller, message and fields prefixes
+// are plain text. Icons are enabled by default.
+func (mk *MakLogger) SetIconsEnabled(enabled bool) {
+	mk.iconsDisabled = !enabled
+}
+
+// IconsEnabled returns whether emoji icons are printed in text output.
+func (mk *MakLogger) IconsEnabled() bool {
+	return !mk.iconsDisabled
+}
+
+// SetTimeFormat sets the layout used to format timestamps in text output,
+// e.g. time.RFC3339. An empty layout omits the timestamp entirely.
+// Structured formats always use RFC 3339 timestamps.
+func (mk *MakLogger) SetTimeFormat(layout string) {
+	mk.timeFormat = layout
+}
+
+// TimeFormat returns the layout used to format timestamps in text output.
+func (mk *MakLogger) TimeFormat() string {
+	return mk.timeFormat
+}
+
+// SetSeparator sets the string written between the segments of a text record,
+// e.g. " | " or "\t" for log viewers that render box-drawing characters poorly.
+func (mk *MakLogger) SetSeparator(separator string) {
+	mk.separator = separator
+}
+
+// Separator returns the separator between the segments of a text record.
+func (mk *MakLogger) Separator() string {
+	return mk.separator
+}
+
+// SetLineEnding sets the string records and the lines of field blocks end
+// with, e.g. "\r\n" for consumers expecting CRLF. The default is "\n".
+func (mk *MakLogger) SetLineEnding(ending string) {
+	mk.lineEnding = ending
+}
+
+// LineEnding returns the string records end with.
+func (mk *MakLogger) LineEnding() string {
+	return mk.lineEnding
+}
+
+// SetUTC sets whether timestamps are converted to UTC before formatting.
+// By default the local time zone is used.
+func (mk *MakLogger) SetUTC(utc bool) {
+	mk.utc = utc
+}
+
+// SetClock sets the function that returns the time of each record, e.g. a
+// fixed time in tests or the original times when replaying events.
+// Passing nil restores the default, time.Now.
+func (mk *MakLogger) SetClock(clock func() time.Time) {
+	mk.clock = clock
+}
+
+// now returns the current time of the logger's clock.
+func (mk *MakLogger) now() time.Time {
+	if mk.clock == nil {
+		return time.Now()
+	}
+	return mk.clock()
+}
+
+// SetCallerEnabled sets whether the file, line and function of the call site
+// are looked up and included in records. Disabling it produces shorter lines
+// and avoids the cost of runtime.Caller on every call. Enabled by default.
+func (mk *MakLogger) SetCallerEnabled(enabled bool) {
+	mk.callerDisabled = !enabled
+}
+
+// CallerEnabled returns whether caller information is included in records.
+func (mk *MakLogger) CallerEnabled() bool {
+	return !mk.callerDisabled
+}
+
+// SetCallerSkip sets the number of additional stack frames to skip when
+// reporting the call site. Use it when wrapping the logger in helper
+// functions: with one extra wrapper, such as a company-wide mylog.Info
+// that calls logger.Info, set skip to 1 so the wrapper's caller is reported.
+func (mk *MakLogger) SetCallerSkip(skip int) {
+	mk.callerSkip = skip
+}
+
+// SetCallerFullPath sets whether records show the full path of the calling
+// file instead of its base name, e.g. to tell apart the main.go files of a
+// monorepo. Use SetCallerTrimPrefix to shorten the paths.
+func (mk *MakLogger) SetCallerFullPath(enabled bool) {
+	mk.callerFullPath = enabled
+}
+
+// SetCallerTrimPrefix sets a prefix, such as the repository root, removed
+// from caller paths shown with SetCallerFullPath.
+func (mk *MakLogger) SetCallerTrimPrefix(prefix string) {
+	mk.callerTrimPrefix = prefix
+}
+
+// SetCallerFullFunc sets whether text records show the full function name of
+// the call site, such as "example.com/app/db.(*Pool).Get", instead of the
+// last segment only, "Get".
+func (mk *MakLogger) SetCallerFullFunc(enabled bool) {
+	mk.callerFullFunc = enabled
+}
+
+// SetMaxRecordBytes limits the size of a single rendered record. Records
+// larger than n bytes are replaced with a short notice stating their size.
+// A zero or negative value disables the limit (the default).
+func (mk *MakLogger) SetMaxRecordBytes(n int) {
+	mk.maxRecordBytes = n
+}
+
+// SetMaxFieldLength limits the length of string field values, including error
+// messages and values in groups. Longer values are cut to n bytes, at a UTF-8
+// character boundary, and followed by "…(truncated, M bytes)" with M the
+// original length. A zero or negative value disables the limit (the default).
+func (mk *MakLogger) SetMaxFieldLength(n int) {
+	mk.maxFieldLength = n
+}
+
+// SetTrailingReset sets whether each output line ends with the ANSI Reset code.
+// When disabled, the trailing Reset is omitted and a single Reset is written
+// on Close instead. Useful when piping into tools that inject their own reset.
+func (mk *MakLogger) SetTrailingReset(enabled bool) {
+	mk.trailingResetDisabled = !enabled
+}
+
+// TrailingReset returns whether output lines end with the ANSI Reset code.
+func (mk *MakLogger) TrailingReset() bool {
+	return !mk.trailingResetDisabled
+}
+
+// AddHook registers a callback invoked synchronously for every record that
+// passes level filtering and sampling, before it is written. Hooks run in
+// registration order and receive all fields of the record, including those
+// added by WithFields, with Lazy values computed and groups as []Field
+// values. They must not modify the fields slice.
+func (mk *MakLogger) AddHook(hook func(level Level, msg string, fields []Field)) {
+	mk.hooks = append(mk.hooks, hook)
+}
+
+// OnError registers a handler for internal diagnostics produced by the logger,
+// such as warnings about slow field formatting. Passing nil removes the handler.
+func (mk *MakLogger) OnError(fn func(error)) {
+	mk.onError = fn
+}
+
+// SetSlowFieldsThreshold enables timing of field formatting in every format.
+// When formatting the fields of a single record takes longer than d, a
+// warning is reported via the OnError handler, at most once per
+// slowFieldsWarnInterval.
+// A zero or negative duration disables the measurement (the default).
+func (mk *MakLogger) SetSlowFieldsThreshold(d time.Duration) {
+	mk.slowFieldsThreshold = d
+}
+
+// slowFieldsWarnInterval is the minimum time between two slow field warnings.
+var slowFieldsWarnInterval = time.Minute
+
+// fieldsTimer returns the time formatting fields starts at, to be passed to
+// checkSlowFields, or the zero time if there are no fields or timing is
+// disabled.
+func (mk *MakLogger) fieldsTimer(fields []Field) time.Time {
+	if mk.slowFieldsThreshold <= 0 || len(fields) == 0 {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// checkSlowFields reports a throttled warning if formatting fields, started
+// at the time returned by fieldsTimer, took too long.
+func (mk *MakLogger) checkSlowFields(start time.Time, count int) {
+	if start.IsZero() || mk.onError == nil {
+		return
+	}
+	elapsed := time.Since(start)
+	if elapsed <= mk.slowFieldsThreshold {
+		return
+	}
+
+	now := time.Now().UnixNano()
+	last := mk.lastSlowFieldsWarn.Load()
+	if last != 0 && now-last < int64(slowFieldsWarnInterval) {
+		return
+	}
+	if !mk.lastSlowFieldsWarn.CompareAndSwap(last, now) {
+		return
+	}
+
+	mk.onError(fmt.Errorf("maklogger: formatting %d fields took %s (threshold %s), consider reducing field size",
+		count, elapsed, mk.slowFieldsThreshold))
+}
+
+// SetName sets the logger name. It is shown as a [name] tag in text output
+// and under the "logger" key in structured output. Loggers derived with
+// WithFields inherit the name.
+func (mk *MakLogger) SetName(name string) {
+	mk.name = name
+}
+
+// Name returns the logger name.
+func (mk *MakLogger) Name() string {
+	return mk.name
+}
+
+// Clone returns a copy of the logger with the same configuration that shares
+// no mutable state with it, e.g. to write to another output or at another
+// level. The clone starts with empty sampling, rate limit and Counts state and
+// its own audit chain. In async mode it gets its own queue and worker, so
+// close it with Close like the original.
+func (mk *MakLogger) Clone() *MakLogger {
+	clone := mk.clone()
+	clone.sampler = mk.sampler.clone()
+	clone.limiter = mk.limiter.clone()
+	clone.audit = mk.audit.clone()
+	clone.writeMu = new(sync.Mutex)
+	clone.counts = new(levelCounts)
+	clone.resetPending = new(atomic.Bool)
+	clone.lastSlowFieldsWarn = new(atomic.Int64)
+
+	clone.async = nil
+	if mk.async != nil {
+		clone.SetAsync(cap(mk.async.queue), mk.async.policy)
+	}
+	return clone
+}
+
+// clone copies the configuration of the logger. Maps are copied and slices
+// clipped, so changing either logger afterwards does not affect the other:
+// setters can update maps in place and append to slices, because a clipped
+// slice is reallocated by the first append to it. The state of the output
+// pipeline is shared: the write lock, async queue, audit chain, sampling and
+// rate limit state and the Counts counters. Loggers derived with WithFields
+// and Named therefore write through their parent's pipeline.
+func (mk *MakLogger) clone() *MakLogger {
+	clone := *mk
+	clone.levelOutputs = maps.Clone(mk.levelOutputs)
+	clone.disabledLevels = maps.Clone(mk.disabledLevels)
+	clone.redactKeys = maps.Clone(mk.redactKeys)
+	clone.theme.Levels = maps.Clone(mk.theme.Levels)
+
+	clone.fields = slices.Clip(mk.fields)
+	clone.sinks = slices.Clip(mk.sinks)
+	clone.hooks = slices.Clip(mk.hooks)
+	return &clone
+}
+
+// WithFields returns a derived logger that includes the given fields in every
+// record. Fields passed to a log call are added after them and override them
+// by key. The derived logger starts with the parent's settings; changing
+// either logger afterwards does not affect the other. It writes through the
+// parent's async queue and audit chain and shares its sampling, rate limit
+// and Counts state.
+func (mk *MakLogger) WithFields(fields ...Field) *MakLogger {
+	child := mk.clone()
+	child.fields = append(append(make([]Field, 0, len(mk.fields)+len(fields)), mk.fields...), fields...)
+	return child
+}
+
+// WithField returns a derived logger that includes the given field in every
+// record, like WithFields with a single field.
+func (mk *MakLogger) WithField(key string, value any) *MakLogger {
+	return mk.WithFields(Field{Key: key, Value: value})
+}
+
+// AddField adds a field to every later record of the logger itself, unlike
+// WithField, which returns a derived logger. Loggers derived earlier are not
+// affected. Like the other setters, call it while setting the logger up, not
+// concurrently with logging.
+func (mk *MakLogger) AddField(key string, value any) {
+	mk.fields = append(mk.fields, Field{Key: key, Value: value})
+}
+
+// RemoveField removes the fields with the given key added by WithFields or
+// AddField from later records of the logger. It does nothing if the key is
+// not set. Like AddField, call it while no other goroutine is logging.
+func (mk *MakLogger) RemoveField(key string) {
+	if !slices.ContainsFunc(mk.fields, func(field Field) bool { return field.Key == key }) {
+		return
+	}
+
+	// Build a new slice, other loggers may share the array
+	fields := make([]Field, 0, len(mk.fields)-1)
+	for _, field := range mk.fields {
+		if field.Key != key {
+			fields = append(fields, field)
+		}
+	}
+	mk.fields = fields
+}
+
+// Named returns a derived logger whose name is the parent's name and the
+// given name joined with a dot, so Named("db").Named("pool") is named
+// "db.pool". Like WithFields, it starts with the parent's settings.
+func (mk *MakLogger) Named(name string) *MakLogger {
+	child := mk.clone()
+	child.name = joinKey(mk.name, name)
+	return child
 }
 
 // log is the core logging method that formats and outputs log messages.
-func (mk *MakLogger) log(level Level, color Color, msg string, fields ...Field) {
-	file, line, fn := getCallerInfo(3)
-
-	// Get detailed information
-	now := time.Now()
-	timestamp := now.Format("2006-01-02 15:04:05.000")
-
-	// Format module and function
-	moduleParts := strings.Split(fn, ".")
-	shortFn := fn
-	if len(moduleParts) > 0 {
-		shortFn = moduleParts[len(moduleParts)-1]
-	}
-
-	// Create beautiful module with icons
-	module := fmt.Sprintf("%s %s:%s %s %s",
-		ColorizeIfEnabled("📁", mk.colorsEnabled, BrightBlue),
-		ColorizeIfEnabled(file, mk.colorsEnabled, Cyan),
-		ColorizeIfEnabled(strconv.Itoa(line), mk.colorsEnabled, BrightCyan),
-		ColorizeIfEnabled("⚡", mk.colorsEnabled, BrightYellow),
-		ColorizeIfEnabled(shortFn, mk.colorsEnabled, Magenta),
-	)
-
-	// Main message without PID (according to specification)
-	message := fmt.Sprintf("%s %s │ %s │ %s │ %s %s",
-		ColorizeIfEnabled("🕒 ", mk.colorsEnabled, BrightGreen),
-		ColorizeIfEnabled(timestamp, mk.colorsEnabled, Green),
-		mk.getColoredLevel(level),
-		module,
-		ColorizeIfEnabled("💬 ", mk.colorsEnabled, BrightWhite),
-		mk.getColoredMessage(level, msg),
-	)
-
-	fmt.Println(message)
+func (mk *MakLogger) log(level Level, msg string, fields ...Field) {
+	if !mk.enabled(level) {
+		return
+	}
+
+	if mk.stackTraceEnabled(level) {
+		fields = appendStack(fields, captureStack(2+mk.callerSkip))
+	}
+
+	r := Record{Time: mk.now(), Level: level, Message: msg, Fields: fields}
+	if !mk.callerDisabled {
+		r.File, r.Line, r.Function = getCallerInfo(2 + mk.callerSkip)
+	}
+	mk.Log(r)
+}
+
+// write formats a record and writes it to the output. An empty file omits
+// the caller information.
+func (mk *MakLogger) write(r Record) {
+	fields, ok := mk.sample(r.Time, mk.withBaseFields(r.Fields))
+	if !ok {
+		return
+	}
+	fields = mk.resolveLazy(fields)
+
+	if mk.limiter != nil {
+		mk.writeSuppressed(r.Time, false)
+		if !mk.limiter.allow(r.Level, r.Time) {
+			return
+		}
+	}
+
+	for _, hook := range mk.hooks {
+		hook(r.Level, r.Message, fields)
+	}
+
+	mk.output(r.Level, r.Time, r.File, r.Line, r.Function, r.Message, fields)
+}
+
+// withBaseFields returns the fields added by WithFields followed by fields.
+func (mk *MakLogger) withBaseFields(fields []Field) []Field {
+	if len(mk.fields) == 0 {
+		return fields
+	}
+	return append(append(make([]Field, 0, len(mk.fields)+len(fields)), mk.fields...), fields...)
+}
+
+// Sprint returns the record that logging msg and fields at the given level
+// would write, formatted with the logger's settings, without writing it.
+// The level threshold, sampling, rate limits and hooks do not apply.
+func (mk *MakLogger) Sprint(level Level, msg string, fields ...Field) string {
+	var file, fn string
+	var line int
+	if !mk.callerDisabled {
+		file, line, fn = getCallerInfo(1 + mk.callerSkip)
+	}
+
+	now := mk.now()
+	if mk.utc {
+		now = now.UTC()
+	}
+
+	fields, _, _ = stripSamplingKeys(mk.withBaseFields(fields))
+
+	b := getBuffer()
+	mk.render(b, level, now, file, line, fn, msg, fields)
+
+	s := b.String()
+	putBuffer(b)
+	return s
+}
+
+// output renders a record that passed filtering and writes it to the output
+// and sinks.
+func (mk *MakLogger) output(level Level, now time.Time, file string, line int, fn string, msg string, fields []Field) {
+	if mk.silent {
+		return
+	}
+	if mk.utc {
+		now = now.UTC()
+	}
+	if mk.otelEmitter != nil {
+		mk.emitOTel(level, now, file, line, fn, msg, fields)
+	}
+	// Audited messages cannot start a line mistaken for an audit trailer;
+	// field values are JSON encoded and never contain raw line breaks
+	if mk.audit != nil {
+		msg = lineBreakEscaper.Replace(msg)
+	}
+
+	b := getBuffer()
+	mk.render(b, level, now, file, line, fn, msg, fields)
+
+	// Replace oversized records with a notice
+	if mk.maxRecordBytes > 0 && b.Len() > mk.maxRecordBytes {
+		size := b.Len()
+		msg = fmt.Sprintf("record dropped: %d bytes exceeds the limit of %d bytes", size, mk.maxRecordBytes)
+		fields = nil
+		b.Reset()
+		mk.render(b, level, now, file, line, fn, msg, fields)
+	}
+
+	// Sinks rendered in another style share one rendering per style
+	var styled []rendering
+	outputStyle := mk.outputStyle()
+	colored := outputStyle.colors
+	for _, sink := range mk.sinks {
+		style := mk.sinkStyle(sink)
+		if style == outputStyle || lookupRendering(styled, style) != nil {
+			continue
+		}
+		styledLogger := *mk
+		styledLogger.format, styledLogger.colorsEnabled = style.format, style.colors
+		sb := getBuffer()
+		styledLogger.render(sb, level, now, file, line, fn, msg, fields)
+		styled = append(styled, rendering{style: style, b: sb})
+		colored = colored || style.colors
+	}
+
+	// Colored lines were written without their trailing Reset
+	if mk.trailingResetDisabled && colored {
+		mk.resetPending.Store(true)
+	}
+
+	r := asyncRecord{logger: mk, level: level, out: mk.levelOutput(level), data: b, styled: styled}
+	if mk.batch != nil {
+		*mk.batch = append(*mk.batch, r)
+		return
+	}
+	mk.submit(r)
+}
+
+// submit writes rendered records in order, or queues them for the async
+// worker, holding the write lock so no other record is written in between.
+func (mk *MakLogger) submit(records ...asyncRecord) {
+	mk.writeMu.Lock()
+	defer mk.writeMu.Unlock()
+
+	for _, r := range records {
+		// Queued records are returned to the pool by the async worker
+		if mk.async != nil && mk.async.enqueue(r) {
+			continue
+		}
+		mk.emit(r)
+		putBuffer(r.data)
+		for _, styled := range r.styled {
+			putBuffer(styled.b)
+		}
+	}
+}
+
+// bufferPool holds the buffers records are rendered into.
+var bufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// maxPooledBufferSize is the capacity above which buffers are not returned to
+// the pool, so a single huge record does not pin its memory.
+const maxPooledBufferSize = 64 << 10
+
+// getBuffer returns an empty buffer from the pool.
+func getBuffer() *bytes.Buffer {
+	b := bufferPool.Get().(*bytes.Buffer)
+	b.Reset()
+	return b
+}
+
+// putBuffer returns a buffer to the pool.
+func putBuffer(b *bytes.Buffer) {
+	if b.Cap() > maxPooledBufferSize {
+		return
+	}
+	bufferPool.Put(b)
+}
+
+// emit writes a rendered record to the sinks and to its output, appending the
+// audit trailer to the output copy if enabled. Sinks rendered in another style
+// than the output receive their rendering from styled. The record is counted
+// once the output, or the stderr fallback, accepted it.
+func (mk *MakLogger) emit(r asyncRecord) {
+	out, b := r.out, r.data
+	outputStyle := mk.outputStyle()
+	for _, sink := range mk.sinks {
+		data := b
+		if style := mk.sinkStyle(sink); style != outputStyle {
+			data = lookupRendering(r.styled, style)
+		}
+		if _, err := sink.w.Write(data.Bytes()); err != nil {
+			mk.writeFailed(sink.w, err)
+		}
+		if mk.flushEach {
+			syncWriter(sink.w)
+		}
+	}
+
+	var err error
+	if mk.audit != nil {
+		_, err = mk.audit.write(out, b, mk.lineEnding)
+	} else {
+		_, err = out.Write(b.Bytes())
+	}
+	if err == nil || mk.outputFailed(out, b.Bytes(), err) {
+		mk.counts.add(r.level)
+	}
+	if mk.flushEach {
+		syncWriter(out)
+	}
+}
+
+// render formats a record into b according to the configured format.
+func (mk *MakLogger) render(b *bytes.Buffer, level Level, now time.Time, file string, line int, fn string, msg string, fields []Field) {
+	switch mk.format {
+	case FormatJSON:
+		mk.renderJSON(b, now, level, file, line, msg, fields)
+		return
+	case FormatLogfmt:
+		mk.renderLogfmt(b, now, level, file, line, msg, fields)
+		return
+	case FormatSyslog:
+		mk.renderSyslog(b, now, level, file, line, msg, fields)
+		return
+	}
+
+	start := b.Len()
+
+	// Timestamp segment, omitted when the time format is empty
+	if mk.timeFormat != "" {
+		mk.writeIcon(b, "🕒 ", BrightGreen)
+		b.WriteString(ColorizeIfEnabled(now.Format(mk.timeFormat), mk.colorsEnabled, Green))
+		b.WriteString(mk.separator)
+	}
+
+	b.WriteString(mk.getColoredLevel(level))
+
+	// Logger name, if any
+	if mk.name != "" {
+		b.WriteString(mk.separator)
+		b.WriteString(ColorizeIfEnabled("["+mk.name+"]", mk.colorsEnabled, BrightCyan))
+	}
+
+	// Module segment, omitted when caller info is disabled
+	if file != "" {
+		// Short function name without the package path, unless configured
+		if !mk.callerFullFunc {
+			fn = fn[strings.LastIndexByte(fn, '.')+1:]
+		}
+
+		b.WriteString(mk.separator)
+		mk.writeIcon(b, "📁", BrightBlue)
+		b.WriteString(ColorizeIfEnabled(mk.callerFile(file), mk.colorsEnabled, Cyan))
+		b.WriteByte(':')
+		b.WriteString(ColorizeIfEnabled(strconv.Itoa(line), mk.colorsEnabled, BrightCyan))
+		b.WriteByte(' ')
+		mk.writeIcon(b, "⚡", BrightYellow)
+		b.WriteString(ColorizeIfEnabled(fn, mk.colorsEnabled, Magenta))
+	}
+
+	b.WriteString(mk.separator)
+	mk.writeIcon(b, "💬 ", BrightWhite)
+	b.WriteString(mk.getColoredMessage(level, msg))
+
+	// Inline fields follow the message on the same line
+	if len(fields) > 0 && mk.fieldStyle == FieldStyleInline {
+		b.WriteByte(' ')
+		mk.writeColoredFields(b, fields, true)
+	}
+	mk.endLine(b, start)
 
 	// Process fields if they exist - display on next line (according to specification)
-	if len(fields) > 0 {
-		fieldStr := mk.formatFieldsAsJSON(fields)
-		fmt.Printf("%s %s\n%s\n",
-			ColorizeIfEnabled("📊 ", mk.colorsEnabled, BrightMagenta),
-			ColorizeIfEnabled("Fields:", mk.colorsEnabled, BrightWhite),
-			ColorizeIfEnabled(fieldStr, mk.colorsEnabled, BrightBlack), // gray color for JSON
-		)
+	if len(fields) > 0 && mk.fieldStyle != FieldStyleInline {
+		start = b.Len()
+		mk.writeIcon(b, "📊 ", BrightMagenta)
+		b.WriteString(ColorizeIfEnabled("Fields:", mk.colorsEnabled, BrightWhite))
+		mk.endLine(b, start)
+
+		start = b.Len()
+		mk.writeColoredFields(b, fields, false)
+		mk.endLine(b, start)
+		if mk.fieldTrailingNewline {
+			b.WriteString(mk.lineEnding)
+		}
+	}
+}
+
+// writeColoredFields writes the fields of a text record in the theme's fields
+// color, gray by default, or colored by type if that is configured, as compact
+// JSON if inline is set and as indented JSON otherwise.
+func (mk *MakLogger) writeColoredFields(b *bytes.Buffer, fields []Field, inline bool) {
+	timer := mk.fieldsTimer(fields)
+
+	// Typed colors are applied to the rendered JSON
+	out := b
+	typed := mk.colorsEnabled && mk.typedFieldColors
+	if typed {
+		out = getBuffer()
+		defer putBuffer(out)
+	} else if mk.colorsEnabled {
+		b.WriteString(string(mk.fieldColor()))
+	}
+	if inline {
+		mk.writeCompactFields(out, fields)
+	} else {
+		mk.writeFields(out, fields)
+	}
+	if typed {
+		writeTypedJSON(b, out.Bytes(), mk.numberGrouping)
+	} else if mk.colorsEnabled {
+		b.WriteString(string(Reset))
 	}
+	mk.checkSlowFields(timer, len(fields))
+}
+
+// writeIcon writes a colored icon followed by a space, unless icons are disabled.
+func (mk *MakLogger) writeIcon(b *bytes.Buffer, icon string, color Color) {
+	if mk.iconsDisabled {
+		return
+	}
+	b.WriteString(ColorizeIfEnabled(icon, mk.colorsEnabled, color))
+	b.WriteByte(' ')
+}
+
+// endLine terminates the line of b starting at start with the line ending,
+// removing its trailing Reset code first if that is configured.
+func (mk *MakLogger) endLine(b *bytes.Buffer, start int) {
+	if mk.trailingResetDisabled && bytes.HasSuffix(b.Bytes()[start:], []byte(Reset)) {
+		b.Truncate(b.Len() - len(Reset))
+	}
+	b.WriteString(mk.lineEnding)
 }
 
 // Info logs an informational message with optional structured fields.
 func (mk *MakLogger) Info(msg string, fields ...Field) {
-	mk.log(LevelInfo, Yellow, msg, fields...)
+	mk.log(LevelInfo, msg, fields...)
 }
 
 // Warn logs a warning message with optional structured fields.
 func (mk *MakLogger) Warn(msg string, fields ...Field) {
-	mk.log(LevelWarn, Yellow, msg, fields...)
+	mk.log(LevelWarn, msg, fields...)
 }
 
 // Error logs an error message with optional structured fields.
 func (mk *MakLogger) Error(msg string, fields ...Field) {
-	mk.log(LevelError, Red, msg, fields...)
+	mk.log(LevelError, msg, fields...)
 }
 
 // Success logs a success message with optional structured fields.
 func (mk *MakLogger) Success(msg string, fields ...Field) {
-	mk.log(LevelSuccess, Red, msg, fields...)
+	mk.log(LevelSuccess, msg, fields...)
 }
 
 // Debug logs a debug message with optional structured fields.
 func (mk *MakLogger) Debug(msg string, fields ...Field) {
-	mk.log(LevelDebug, Red, msg, fields...)
+	mk.log(LevelDebug, msg, fields...)
 }
 
 // Critical logs a critical message with optional structured fields.
 func (mk *MakLogger) Critical(msg string, fields ...Field) {
-	mk.log(LevelCritical, Red, msg, fields...)
+	mk.log(LevelCritical, msg, fields...)
+}
+
+// exitFunc terminates the process after a Fatal record. Tests override it.
+var exitFunc = os.Exit
+
+// exit flushes pending output and exits the process with code.
+func (mk *MakLogger) exit(code int) {
+	mk.Flush()
+	exitFunc(code)
+}
+
+// SetFatalExitCode sets the status the process exits with after a Fatal
+// record or a fatal assertion. The default is 1.
+func (mk *MakLogger) SetFatalExitCode(code int) {
+	mk.fatalExitCode = code
+}
+
+// FatalExitCode returns the status the process exits with after a Fatal record.
+func (mk *MakLogger) FatalExitCode() int {
+	return mk.fatalExitCode
+}
+
+// Fatal logs a fatal message with optional structured fields,
+// flushes pending output and exits the process with the fatal exit code.
+func (mk *MakLogger) Fatal(msg string, fields ...Field) {
+	mk.log(LevelFatal, msg, fields...)
+	mk.exit(mk.fatalExitCode)
+}
+
+// FatalCode logs a fatal message with optional structured fields,
+// flushes pending output and exits the process with code.
+func (mk *MakLogger) FatalCode(code int, msg string, fields ...Field) {
+	mk.log(LevelFatal, msg, fields...)
+	mk.exit(code)
+}
+
+// Panic logs a panic message with optional structured fields,
+// flushes pending output and then panics with msg.
+func (mk *MakLogger) Panic(msg string, fields ...Field) {
+	mk.log(LevelPanic, msg, fields...)
+	mk.Flush()
+	panic(msg)
+}
+
+// Assert checks a runtime invariant. When cond is false it logs
+// "assertion failed: msg" at the assert level (Critical by default).
+// Execution continues unless SetAssertFatal(true) was called.
+func (mk *MakLogger) Assert(cond bool, msg string, fields ...Field) {
+	if cond {
+		return
+	}
+	mk.log(mk.assertLevel, "assertion failed: "+msg, fields...)
+	if mk.assertFatal {
+		mk.exit(mk.fatalExitCode)
+	}
+}
+
+// SetAssertLevel sets the level failed assertions are logged at.
+func (mk *MakLogger) SetAssertLevel(level Level) {
+	mk.assertLevel = level
+}
+
+// SetAssertFatal sets whether a failed assertion exits the process with the
+// fatal exit code.
+func (mk *MakLogger) SetAssertFatal(fatal bool) {
+	mk.assertFatal = fatal
+}
+
+// Infof logs a formatted informational message.
+func (mk *MakLogger) Infof(format string, args ...any) {
+	mk.log(LevelInfo, fmt.Sprintf(format, args...))
+}
+
+// Warnf logs a formatted warning message.
+func (mk *MakLogger) Warnf(format string, args ...any) {
+	mk.log(LevelWarn, fmt.Sprintf(format, args...))
+}
+
+// Errorf logs a formatted error message.
+func (mk *MakLogger) Errorf(format string, args ...any) {
+	mk.log(LevelError, fmt.Sprintf(format, args...))
+}
+
+// Successf logs a formatted success message.
+func (mk *MakLogger) Successf(format string, args ...any) {
+	mk.log(LevelSuccess, fmt.Sprintf(format, args...))
+}
+
+// Debugf logs a formatted debug message.
+func (mk *MakLogger) Debugf(format string, args ...any) {
+	mk.log(LevelDebug, fmt.Sprintf(format, args...))
+}
+
+// Criticalf logs a formatted critical message.
+func (mk *MakLogger) Criticalf(format string, args ...any) {
+	mk.log(LevelCritical, fmt.Sprintf(format, args...))
+}
+
+// Fatalf logs a formatted fatal message, flushes pending output
+// and exits the process with the fatal exit code.
+func (mk *MakLogger) Fatalf(format string, args ...any) {
+	mk.log(LevelFatal, fmt.Sprintf(format, args...))
+	mk.exit(mk.fatalExitCode)
+}
+
+// InfoIf logs an informational message only if cond is true.
+func (mk *MakLogger) InfoIf(cond bool, msg string, fields ...Field) {
+	if cond {
+		mk.log(LevelInfo, msg, fields...)
+	}
+}
+
+// SuccessIf logs a success message only if cond is true.
+func (mk *MakLogger) SuccessIf(cond bool, msg string, fields ...Field) {
+	if cond {
+		mk.log(LevelSuccess, msg, fields...)
+	}
+}
+
+// DebugIf logs a debug message only if cond is true.
+func (mk *MakLogger) DebugIf(cond bool, msg string, fields ...Field) {
+	if cond {
+		mk.log(LevelDebug, msg, fields...)
+	}
+}
+
+// WarnIf logs a warning message only if cond is true.
+func (mk *MakLogger) WarnIf(cond bool, msg string, fields ...Field) {
+	if cond {
+		mk.log(LevelWarn, msg, fields...)
+	}
+}
+
+// ErrorIf logs an error message only if cond is true.
+func (mk *MakLogger) ErrorIf(cond bool, msg string, fields ...Field) {
+	if cond {
+		mk.log(LevelError, msg, fields...)
+	}
+}
+
+// CriticalIf logs a critical message only if cond is true.
+func (mk *MakLogger) CriticalIf(cond bool, msg string, fields ...Field) {
+	if cond {
+		mk.log(LevelCritical, msg, fields...)
+	}
 }
 
 // formatFieldsAsJSON formats fields into a beautiful JSON string (according to specification with 2-space indentation).
@@ -179,77 +1060,46 @@ func (mk *MakLogger) formatFieldsAsJSON(fields []Field) string {
 		return ""
 	}
 
-	// Create map for JSON serialization
-	fieldMap := make(map[string]interface{})
-	for _, field := range fields {
-		fieldMap[field.Key] = field.Value
-	}
+	b := getBuffer()
+	defer putBuffer(b)
+	mk.writeFields(b, fields)
+	return b.String()
+}
 
-	// Serialize to beautiful JSON with indentation (json.MarshalIndent with 2-space indentation)
-	jsonBytes, err := json.MarshalIndent(fieldMap, "  ", "  ")
-	if err != nil {
-		return fmt.Sprintf(`  {
+// writeFields writes fields to b as indented JSON, with every line prefixed
+// by the fields base indent and lines separated by the line ending.
+func (mk *MakLogger) writeFields(b *bytes.Buffer, fields []Field) {
+	compact := getBuffer()
+	defer putBuffer(compact)
+	mk.writeCompactFields(compact, fields)
+
+	// Indent the JSON (2-space indentation, same layout as json.MarshalIndent),
+	// prefixing each line with the base indent for beautiful output
+	base := strings.Repeat(" ", mk.fieldsBaseIndent)
+	start := b.Len()
+	b.WriteString(base)
+	if err := json.Indent(b, compact.Bytes(), base+"  ", "  "); err != nil {
+		b.Truncate(start)
+		fmt.Fprintf(b, `  {
     "error": "failed to marshal fields: %v"
   }`, err)
 	}
 
-	// Add indentation to each JSON line for beautiful output
-	lines := strings.Split(string(jsonBytes), "\n")
-	for i, line := range lines {
-		lines[i] = "  " + line
-	}
-
-	return strings.Join(lines, "\n")
-}
-
-// getColoredLevel returns a formatted log level with color settings.
-func (mk *MakLogger) getColoredLevel(level Level) string {
-	switch level {
-	case LevelInfo:
-		return fmt.Sprintf("%s %s",
-			ColorizeIfEnabled("📝 ", mk.colorsEnabled, BrightBlue),
-			ColorizeIfEnabled("INFO    ", mk.colorsEnabled, BoldWhite, BgBlue))
-	case LevelSuccess:
-		return fmt.Sprintf("%s %s",
-			ColorizeIfEnabled("✅ ", mk.colorsEnabled, BrightGreen),
-			ColorizeIfEnabled("SUCCESS ", mk.colorsEnabled, BoldWhite, BgGreen))
-	case LevelDebug:
-		return fmt.Sprintf("%s %s",
-			ColorizeIfEnabled("🐛 ", mk.colorsEnabled, BrightMagenta),
-			ColorizeIfEnabled("DEBUG   ", mk.colorsEnabled, BoldWhite, BgMagenta))
-	case LevelCritical:
-		return fmt.Sprintf("%s %s",
-			ColorizeIfEnabled("🛑 ", mk.colorsEnabled, BrightRed),
-			ColorizeIfEnabled("CRITICAL", mk.colorsEnabled, BoldWhite, BgBrightRed))
-	case LevelError:
-		return fmt.Sprintf("%s %s",
-			ColorizeIfEnabled("❌ ", mk.colorsEnabled, BrightRed),
-			ColorizeIfEnabled("ERROR   ", mk.colorsEnabled, BoldWhite, BgRed))
-	case LevelWarn:
-		return fmt.Sprintf("%s %s",
-			ColorizeIfEnabled("⚠️ ", mk.colorsEnabled, BrightYellow),
-			ColorizeIfEnabled("WARNING ", mk.colorsEnabled, Bold, BgYellow))
-	}
-
-	return "UNDEFINED"
-}
-
-// getColoredMessage returns a formatted message with color settings.
-func (mk *MakLogger) getColoredMessage(level Level, message string) string {
-	switch level {
-	case LevelInfo:
-		return ColorizeIfEnabled(message, mk.colorsEnabled, BrightWhite)
-	case LevelSuccess:
-		return ColorizeIfEnabled(message, mk.colorsEnabled, BrightGreen)
-	case LevelDebug:
-		return ColorizeIfEnabled(message, mk.colorsEnabled, BrightMagenta)
-	case LevelCritical:
-		return ColorizeIfEnabled(message, mk.colorsEnabled, BrightRed, BgBlack)
-	case LevelError:
-		return ColorizeIfEnabled(message, mk.colorsEnabled, BrightRed)
-	case LevelWarn:
-		return ColorizeIfEnabled(message, mk.colorsEnabled, BrightYellow)
-	}
-
-	return "UNDEFINED"
+	// Lines are broken with "\n" above
+	if mk.lineEnding != "\n" {
+		lines := bytes.ReplaceAll(b.Bytes()[start:], []byte("\n"), []byte(mk.lineEnding))
+		b.Truncate(start)
+		b.Write(lines)
+	}
+}
+
+// writeCompactFields writes fields to b as single-line JSON.
+func (mk *MakLogger) writeCompactFields(b *bytes.Buffer, fields []Field) {
+	entries := mk.fieldEntries(fields, false)
+	if mk.numberGrouping {
+		for i := range entries {
+			entries[i].value = groupNumber(entries[i].value)
+		}
+	}
+	writeJSONObject(b, entries)
 }
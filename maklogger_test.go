@@ -2,10 +2,24 @@ package maklogger
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"log"
+	"log/slog"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
+	"unicode/utf8"
 )
 
 // captureOutput captures stdout for testing log output
@@ -30,272 +44,4141 @@ func TestNewLogger(t *testing.T) {
 		t.Fatal("NewLogger() returned nil")
 	}
 
-	// By default, colors should be enabled
+	// By default, colors should be enabled only when stdout is a terminal
+	if logger.ColorsEnabled() != isTerminal(os.Stdout) {
+		t.Errorf("Expected colors enabled=%v for stdout, got: %v", isTerminal(os.Stdout), logger.ColorsEnabled())
+	}
+}
+
+func TestColorDetection(t *testing.T) {
+	logger := NewLogger()
+
+	// A buffer is not a terminal, so colors are turned off
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+	if logger.ColorsEnabled() {
+		t.Error("Colors should be disabled for a non-terminal writer")
+	}
+
+	// A regular file is not a terminal either
+	file, err := os.CreateTemp(t.TempDir(), "maklogger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	logger.SetOutput(file)
+	if logger.ColorsEnabled() {
+		t.Error("Colors should be disabled for a regular file")
+	}
+
+	// An explicit setting overrides detection
+	logger.SetColorsEnabled(true)
+	logger.SetOutput(&buf)
+	if !logger.ColorsEnabled() {
+		t.Error("Explicitly enabled colors should survive SetOutput")
+	}
+}
+
+func TestNoColorEnv(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+
+	logger := NewLogger()
+	if logger.ColorsEnabled() {
+		t.Error("Colors should be disabled when NO_COLOR is set")
+	}
+
+	// Colors can still be enabled explicitly
+	logger.SetColorsEnabled(true)
+	if !logger.ColorsEnabled() {
+		t.Error("Colors should be enabled after SetColorsEnabled(true)")
+	}
+}
+
+func TestSetColorsEnabled(t *testing.T) {
+	logger := NewLogger()
+
+	// Test disabling colors
+	logger.SetColorsEnabled(false)
+	if logger.ColorsEnabled() {
+		t.Error("Colors should be disabled")
+	}
+
+	// Test enabling colors
+	logger.SetColorsEnabled(true)
 	if !logger.ColorsEnabled() {
-		t.Error("Colors should be enabled by default")
+		t.Error("Colors should be enabled")
+	}
+}
+
+func TestSetOutput(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+
+	// By default, output should go to stdout
+	if logger.Output() != os.Stdout {
+		t.Error("Output should default to os.Stdout")
+	}
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+	if logger.Output() != &buf {
+		t.Error("Output should return the configured writer")
+	}
+
+	output := captureOutput(func() {
+		logger.Info("buffered message", Field{Key: "user_id", Value: 123})
+	})
+
+	if output != "" {
+		t.Errorf("Expected nothing on stdout, got: %s", output)
+	}
+
+	if !strings.Contains(buf.String(), "buffered message") {
+		t.Errorf("Expected buffer to contain the message, got: %s", buf.String())
+	}
+
+	if !strings.Contains(buf.String(), "user_id") {
+		t.Errorf("Expected buffer to contain the fields, got: %s", buf.String())
+	}
+
+	// Resetting to nil restores stdout
+	logger.SetOutput(nil)
+	if logger.Output() != os.Stdout {
+		t.Error("SetOutput(nil) should restore os.Stdout")
+	}
+}
+
+func TestSetBufferedOutput(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+
+	var buf bytes.Buffer
+	logger.SetBufferedOutput(&buf, 4096)
+	logger.Info("buffered message")
+
+	if buf.Len() != 0 {
+		t.Errorf("Expected no output before Flush, got: %s", buf.String())
+	}
+
+	if err := logger.Flush(); err != nil {
+		t.Fatalf("Flush returned error: %v", err)
+	}
+
+	if !strings.Contains(buf.String(), "buffered message") {
+		t.Errorf("Expected output after Flush, got: %s", buf.String())
+	}
+
+	logger.Info("closed message")
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	if !strings.Contains(buf.String(), "closed message") {
+		t.Errorf("Expected output after Close, got: %s", buf.String())
+	}
+}
+
+func TestSetBufferedOutputConcurrentFlush(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+
+	var buf bytes.Buffer
+	logger.SetBufferedOutput(&buf, 256)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				logger.Info("concurrent message")
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 100; j++ {
+			logger.Flush()
+		}
+	}()
+	wg.Wait()
+
+	if err := logger.Flush(); err != nil {
+		t.Fatalf("Flush returned error: %v", err)
+	}
+	if n := strings.Count(buf.String(), "concurrent message"); n != 400 {
+		t.Errorf("Expected 400 records, got %d", n)
+	}
+}
+
+func TestSetOutputFlushesBuffered(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+
+	var first, second bytes.Buffer
+	logger.SetBufferedOutput(&first, 4096)
+	logger.Info("first message")
+
+	logger.SetBufferedOutput(&second, 4096)
+	if !strings.Contains(first.String(), "first message") {
+		t.Errorf("Expected pending record to be flushed on SetBufferedOutput, got: %q", first.String())
+	}
+
+	logger.Info("second message")
+	logger.SetOutput(io.Discard)
+	if !strings.Contains(second.String(), "second message") {
+		t.Errorf("Expected pending record to be flushed on SetOutput, got: %q", second.String())
+	}
+}
+
+func TestSetMaxRecordBytes(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+	logger.SetMaxRecordBytes(1024)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+
+	logger.Info("small record", Field{Key: "user_id", Value: 1})
+	if !strings.Contains(buf.String(), "user_id") {
+		t.Errorf("Expected small record to be written unchanged, got: %s", buf.String())
+	}
+
+	buf.Reset()
+	logger.Info("huge record", Field{Key: "payload", Value: strings.Repeat("x", 10000)})
+
+	output := buf.String()
+	if strings.Contains(output, "xxxxxxxxxx") {
+		t.Errorf("Expected oversized record to be dropped, got %d bytes", len(output))
+	}
+
+	if !strings.Contains(output, "record dropped") || !strings.Contains(output, "limit of 1024 bytes") {
+		t.Errorf("Expected a replacement notice, got: %s", output)
+	}
+}
+
+func TestSetClock(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+	logger.SetCallerEnabled(false)
+	logger.SetIconsEnabled(false)
+
+	fixed := time.Date(2024, 3, 1, 12, 30, 45, 123000000, time.UTC)
+	logger.SetClock(func() time.Time { return fixed })
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+	logger.Info("fixed time")
+
+	want := "2024-03-01 12:30:45.123 │ INFO     │ fixed time\n"
+	if buf.String() != want {
+		t.Errorf("Expected %q, got: %q", want, buf.String())
+	}
+	if got := logger.Sprint(LevelInfo, "fixed time"); got != want {
+		t.Errorf("Expected Sprint to use the clock, got: %q", got)
+	}
+
+	buf.Reset()
+	logger.InfoContext(context.Background(), "context")
+	if !strings.HasPrefix(buf.String(), "2024-03-01 12:30:45.123 ") {
+		t.Errorf("Expected InfoContext to use the clock, got: %q", buf.String())
+	}
+}
+
+func TestSetTrailingReset(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(true)
+
+	if !logger.TrailingReset() {
+		t.Error("Trailing reset should be enabled by default")
+	}
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+	logger.Info("with reset")
+	if !strings.HasSuffix(buf.String(), string(Reset)+"\n") {
+		t.Errorf("Expected line to end with Reset, got: %q", buf.String())
+	}
+
+	buf.Reset()
+	logger.SetTrailingReset(false)
+	logger.Info("without reset", Field{Key: "user_id", Value: 1})
+
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		if strings.HasSuffix(line, string(Reset)) {
+			t.Errorf("Expected line to omit the trailing Reset, got: %q", line)
+		}
+	}
+
+	// The reset is emitted once on Close
+	buf.Reset()
+	logger.Close()
+	if buf.String() != string(Reset) {
+		t.Errorf("Expected a single Reset on Close, got: %q", buf.String())
+	}
+}
+
+func TestTrailingResetPending(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(true)
+	logger.SetTrailingReset(false)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+
+	// Sprint renders without leaving a pending reset
+	logger.Sprint(LevelInfo, "rendered only")
+	logger.Close()
+	if buf.Len() != 0 {
+		t.Errorf("Expected no Reset after Sprint, got: %q", buf.String())
+	}
+
+	// A colored sink of a plain logger, logged through a derived logger
+	logger.SetColorsEnabled(false)
+	var sinkBuf bytes.Buffer
+	logger.AddSink(&sinkBuf, true)
+	logger.WithFields(String("user", "bob")).Info("from child")
+
+	sinkBuf.Reset()
+	logger.Close()
+	if sinkBuf.String() != string(Reset) {
+		t.Errorf("Expected a single Reset on the colored sink, got: %q", sinkBuf.String())
+	}
+	if strings.Contains(buf.String(), string(Reset)) {
+		t.Errorf("Expected no Reset on the plain output, got: %q", buf.String())
+	}
+}
+
+// syncBuffer is a bytes.Buffer that counts Sync calls.
+type syncBuffer struct {
+	bytes.Buffer
+	syncs int
+}
+
+func (b *syncBuffer) Sync() error {
+	b.syncs++
+	return nil
+}
+
+func TestSetFlushEach(t *testing.T) {
+	logger := NewLogger()
+
+	var buf syncBuffer
+	logger.SetOutput(&buf)
+
+	logger.Info("not synced")
+	if buf.syncs != 0 {
+		t.Errorf("Expected no Sync calls by default, got: %d", buf.syncs)
+	}
+
+	logger.SetFlushEach(true)
+	logger.Info("first", Field{Key: "user_id", Value: 1})
+	logger.Info("second")
+	logger.Info("third")
+
+	if buf.syncs != 3 {
+		t.Errorf("Expected one Sync call per record, got: %d", buf.syncs)
+	}
+
+	// Buffered output is flushed after every record
+	var target bytes.Buffer
+	logger.SetBufferedOutput(&target, 4096)
+	logger.Info("flushed immediately")
+
+	if !strings.Contains(target.String(), "flushed immediately") {
+		t.Errorf("Expected buffered record to be flushed, got: %q", target.String())
+	}
+}
+
+func TestLogLevels(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false) // Disable colors for easier testing
+
+	tests := []struct {
+		name     string
+		logFunc  func(string, ...Field)
+		message  string
+		expected string
+	}{
+		{"Info", logger.Info, "test info message", "INFO"},
+		{"Success", logger.Success, "test success message", "SUCCESS"},
+		{"Debug", logger.Debug, "test debug message", "DEBUG"},
+		{"Warn", logger.Warn, "test warn message", "WARNING"},
+		{"Error", logger.Error, "test error message", "ERROR"},
+		{"Critical", logger.Critical, "test critical message", "CRITICAL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := captureOutput(func() {
+				tt.logFunc(tt.message)
+			})
+
+			if !strings.Contains(output, tt.expected) {
+				t.Errorf("Expected output to contain '%s', got: %s", tt.expected, output)
+			}
+
+			if !strings.Contains(output, tt.message) {
+				t.Errorf("Expected output to contain message '%s', got: %s", tt.message, output)
+			}
+		})
+	}
+}
+
+func TestFormattedLogLevels(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+
+	tests := []struct {
+		name     string
+		logFunc  func(string, ...any)
+		expected string
+	}{
+		{"Infof", logger.Infof, "INFO"},
+		{"Successf", logger.Successf, "SUCCESS"},
+		{"Debugf", logger.Debugf, "DEBUG"},
+		{"Warnf", logger.Warnf, "WARNING"},
+		{"Errorf", logger.Errorf, "ERROR"},
+		{"Criticalf", logger.Criticalf, "CRITICAL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger.SetOutput(&buf)
+			tt.logFunc("code=%d", 42)
+
+			output := buf.String()
+			if !strings.Contains(output, tt.expected) {
+				t.Errorf("Expected output to contain '%s', got: %s", tt.expected, output)
+			}
+
+			if !strings.Contains(output, "code=42") {
+				t.Errorf("Expected output to contain 'code=42', got: %s", output)
+			}
+
+			// The reported caller must be the test, not the formatting wrapper
+			if !strings.Contains(output, "maklogger_test.go") {
+				t.Errorf("Expected caller to be the test file, got: %s", output)
+			}
+		})
+	}
+}
+
+func TestFatal(t *testing.T) {
+	oldExit := exitFunc
+	defer func() { exitFunc = oldExit }()
+
+	var exitCode int
+	exitFunc = func(code int) {
+		exitCode = code
+	}
+
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+
+	var buf bytes.Buffer
+	logger.SetBufferedOutput(&buf, 4096)
+	logger.Fatal("startup failed", Field{Key: "reason", Value: "missing config"})
+
+	if exitCode != 1 {
+		t.Errorf("Expected exit code 1, got: %d", exitCode)
+	}
+
+	// Output must be flushed before exiting
+	output := buf.String()
+	for _, want := range []string{"FATAL", "startup failed", "missing config"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain '%s', got: %s", want, output)
+		}
+	}
+
+	buf.Reset()
+	exitCode = 0
+	logger.Fatalf("code=%d", 42)
+
+	if exitCode != 1 || !strings.Contains(buf.String(), "code=42") {
+		t.Errorf("Expected Fatalf to log and exit, got code %d and output: %s", exitCode, buf.String())
+	}
+}
+
+func TestFatalExitCode(t *testing.T) {
+	oldExit := exitFunc
+	defer func() { exitFunc = oldExit }()
+
+	var exitCode int
+	exitFunc = func(code int) {
+		exitCode = code
+	}
+
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+	logger.SetOutput(io.Discard)
+
+	if logger.FatalExitCode() != 1 {
+		t.Errorf("Expected default fatal exit code 1, got: %d", logger.FatalExitCode())
+	}
+
+	logger.SetFatalExitCode(3)
+	logger.SetAssertFatal(true)
+	exits := map[string]func(){
+		"Fatal":        func() { logger.Fatal("fatal") },
+		"Fatalf":       func() { logger.Fatalf("fatal %d", 1) },
+		"FatalContext": func() { logger.FatalContext(context.Background(), "fatal") },
+		"Assert":       func() { logger.Assert(false, "invariant") },
+	}
+	for name, exit := range exits {
+		exitCode = 0
+		exit()
+		if exitCode != 3 {
+			t.Errorf("Expected %s to exit with code 3, got: %d", name, exitCode)
+		}
+	}
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+	logger.FatalCode(75, "temporary failure", String("reason", "database unavailable"))
+	if exitCode != 75 {
+		t.Errorf("Expected FatalCode to exit with code 75, got: %d", exitCode)
+	}
+	if !strings.Contains(buf.String(), "FATAL") || !strings.Contains(buf.String(), "database unavailable") {
+		t.Errorf("Expected FatalCode to log the record, got: %s", buf.String())
+	}
+
+	original := Default()
+	defer SetDefault(original)
+	SetDefault(logger)
+	FatalCode(4, "default logger")
+	if exitCode != 4 {
+		t.Errorf("Expected package-level FatalCode to exit with code 4, got: %d", exitCode)
+	}
+	Fatal("default logger")
+	if exitCode != 3 {
+		t.Errorf("Expected package-level Fatal to use the logger's exit code 3, got: %d", exitCode)
+	}
+}
+
+func TestPanic(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+
+	var buf bytes.Buffer
+	logger.SetBufferedOutput(&buf, 4096)
+
+	defer func() {
+		r := recover()
+		if r != "handler crashed" {
+			t.Errorf("Expected panic value 'handler crashed', got: %v", r)
+		}
+
+		// Output must be written before the panic fires
+		output := buf.String()
+		for _, want := range []string{"PANIC", "handler crashed", "request_id", "req-42"} {
+			if !strings.Contains(output, want) {
+				t.Errorf("Expected output to contain '%s', got: %s", want, output)
+			}
+		}
+	}()
+
+	logger.Panic("handler crashed", Field{Key: "request_id", Value: "req-42"})
+	t.Error("Panic should not return")
+}
+
+func TestAssert(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+
+	logger.Assert(true, "never logged")
+	if buf.Len() != 0 {
+		t.Errorf("Expected no output for a true condition, got: %s", buf.String())
+	}
+
+	logger.Assert(1+1 == 3, "math is broken", Field{Key: "expected", Value: 2})
+	output := buf.String()
+	for _, want := range []string{"CRITICAL", "assertion failed: math is broken", "expected", "maklogger_test.go"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain '%s', got: %s", want, output)
+		}
+	}
+
+	// Failed assertions can be configured to exit
+	oldExit := exitFunc
+	defer func() { exitFunc = oldExit }()
+
+	exitCode := -1
+	exitFunc = func(code int) {
+		exitCode = code
+	}
+
+	buf.Reset()
+	logger.SetAssertLevel(LevelError)
+	logger.SetAssertFatal(true)
+	logger.Assert(false, "fatal assertion")
+
+	if exitCode != 1 {
+		t.Errorf("Expected exit code 1, got: %d", exitCode)
+	}
+	if !strings.Contains(buf.String(), "ERROR") {
+		t.Errorf("Expected assertion to be logged at ERROR, got: %s", buf.String())
+	}
+}
+
+func TestSetLevel(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+
+	// By default, every level is logged
+	if logger.Level() != LevelDebug {
+		t.Errorf("Expected default level to be LevelDebug, got: %d", logger.Level())
+	}
+
+	logger.SetLevel(LevelWarn)
+	if logger.Level() != LevelWarn {
+		t.Errorf("Expected level to be LevelWarn, got: %d", logger.Level())
+	}
+
+	tests := []struct {
+		name    string
+		logFunc func(string, ...Field)
+		logged  bool
+	}{
+		{"Debug", logger.Debug, false},
+		{"Info", logger.Info, false},
+		{"Success", logger.Success, false},
+		{"Warn", logger.Warn, true},
+		{"Error", logger.Error, true},
+		{"Critical", logger.Critical, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger.SetOutput(&buf)
+			tt.logFunc("x")
+
+			if (buf.Len() > 0) != tt.logged {
+				t.Errorf("Expected logged=%v, got output: %q", tt.logged, buf.String())
+			}
+		})
+	}
+}
+
+func TestSetSilent(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+
+	var buf, sinkBuf bytes.Buffer
+	logger.SetOutput(&buf)
+	logger.AddSink(&sinkBuf, false)
+	logger.SetMaxPerSecond(LevelInfo, 1)
+
+	var hooked []string
+	logger.AddHook(func(level Level, msg string, fields []Field) {
+		hooked = append(hooked, msg)
+	})
+
+	logger.SetSilent(true)
+	if !logger.Silent() {
+		t.Error("Expected Silent() to report true")
+	}
+	logger.Info("first")
+	logger.Info("rate limited")
+	logger.Error("second")
+	logger.Flush()
+
+	if buf.Len() != 0 || sinkBuf.Len() != 0 {
+		t.Errorf("Expected no output while silent, got: %q and %q", buf.String(), sinkBuf.String())
+	}
+	if len(hooked) != 2 || hooked[0] != "first" || hooked[1] != "second" {
+		t.Errorf("Expected hooks to run while silent, got: %q", hooked)
+	}
+
+	logger.SetSilent(false)
+	logger.Error("audible")
+	if !strings.Contains(buf.String(), "audible") {
+		t.Errorf("Expected output after SetSilent(false), got: %s", buf.String())
+	}
+}
+
+func TestLevelOff(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+
+	var hooked int
+	logger.AddHook(func(level Level, msg string, fields []Field) { hooked++ })
+
+	level, err := ParseLevel("off")
+	if err != nil || level != LevelOff || level.String() != "OFF" {
+		t.Fatalf("Expected ParseLevel(\"off\") to return LevelOff, got: %v, %v", level, err)
+	}
+
+	logger.SetLevel(LevelOff)
+	logger.Debug("debug")
+	logger.Critical("critical")
+	logger.Log(Record{Level: LevelPanic, Message: "panic"})
+	if buf.Len() != 0 || hooked != 0 {
+		t.Errorf("Expected LevelOff to drop all records, got %d hook calls and: %s", hooked, buf.String())
+	}
+
+	// LevelOff is not a level of records
+	logger.SetLevel(LevelDebug)
+	logger.Log(Record{Level: LevelOff, Message: "off"})
+	if buf.Len() != 0 {
+		t.Errorf("Expected records at LevelOff to be dropped, got: %s", buf.String())
+	}
+}
+
+func TestDisableLevel(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+	logger.DisableLevel(LevelDebug)
+	logger.DisableLevel(LevelSuccess)
+
+	tests := []struct {
+		name    string
+		logFunc func(string, ...Field)
+		logged  bool
+	}{
+		{"Debug", logger.Debug, false},
+		{"Info", logger.Info, true},
+		{"Success", logger.Success, false},
+		{"Error", logger.Error, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger.SetOutput(&buf)
+			tt.logFunc("x")
+
+			if (buf.Len() > 0) != tt.logged {
+				t.Errorf("Expected logged=%v, got output: %q", tt.logged, buf.String())
+			}
+		})
+	}
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+	logger.EnableLevel(LevelSuccess)
+	logger.Success("enabled again")
+	if !strings.Contains(buf.String(), "enabled again") {
+		t.Errorf("Expected EnableLevel to turn the level back on, got: %q", buf.String())
+	}
+}
+
+func TestCounts(t *testing.T) {
+	logger := NewLogger()
+	logger.SetOutput(io.Discard)
+	logger.SetLevel(LevelInfo)
+
+	logger.Debug("filtered")
+	logger.Info("one")
+	logger.Info("two")
+	logger.WithFields(String("user", "bob")).Error("three")
+	logger.Critical("four")
+
+	want := map[Level]uint64{LevelInfo: 2, LevelError: 1, LevelCritical: 1}
+	got := logger.Counts()
+	if len(got) != len(want) {
+		t.Errorf("Counts() = %v, want %v", got, want)
+	}
+	for level, n := range want {
+		if got[level] != n {
+			t.Errorf("Counts()[%s] = %d, want %d", level, got[level], n)
+		}
+	}
+}
+
+func TestCountsWrittenRecords(t *testing.T) {
+	// Records dropped by a full async queue are not counted
+	logger := NewLogger()
+	w := &blockingWriter{started: make(chan struct{}, 1), release: make(chan struct{})}
+	logger.SetOutput(w)
+	logger.SetAsync(1, QueueDrop)
+
+	logger.Info("first")
+	<-w.started // the worker is blocked writing the first record
+	logger.Info("second")
+	logger.Info("dropped")
+	if n := logger.Counts()[LevelInfo]; n != 0 {
+		t.Errorf("Expected no records counted before they are written, got %d", n)
+	}
+	close(w.release)
+	logger.Close()
+	if n := logger.Counts()[LevelInfo]; n != 2 {
+		t.Errorf("Expected the 2 written records to be counted, got %d", n)
+	}
+
+	// Failed writes are counted only if the stderr fallback wrote the record
+	var fallback bytes.Buffer
+	oldStderr := stderr
+	stderr = &fallback
+	defer func() { stderr = oldStderr }()
+
+	logger = NewLogger()
+	logger.SetOutput(failingWriter{err: syscall.EPIPE})
+	logger.Error("lost")
+	if n := logger.Counts()[LevelError]; n != 0 {
+		t.Errorf("Expected failed writes not to be counted, got %d", n)
+	}
+	logger.SetFallbackToStderr(true)
+	logger.Error("rescued")
+	if n := logger.Counts()[LevelError]; n != 1 {
+		t.Errorf("Expected the record written to stderr to be counted, got %d", n)
+	}
+
+	// Rate limit summaries are not counted
+	logger = NewLogger()
+	logger.SetOutput(io.Discard)
+	logger.SetMaxPerSecond(LevelInfo, 1)
+	start := time.Now()
+	logger.Log(Record{Time: start, Level: LevelInfo, Message: "kept"})
+	logger.Log(Record{Time: start, Level: LevelInfo, Message: "suppressed"})
+	logger.Log(Record{Time: start.Add(time.Second), Level: LevelInfo, Message: "after window"})
+	if n := logger.Counts()[LevelInfo]; n != 2 {
+		t.Errorf("Expected only the 2 written records to be counted, got %d", n)
+	}
+}
+
+func TestRegisterLevelAlias(t *testing.T) {
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Fatal("Expected error for unregistered alias")
+	}
+
+	RegisterLevelAlias("verbose", LevelDebug)
+	RegisterLevelAlias("ERR", LevelError)
+	t.Cleanup(func() {
+		unregisterLevelAlias("verbose")
+		unregisterLevelAlias("ERR")
+	})
+
+	level, err := ParseLevel("verbose")
+	if err != nil {
+		t.Fatalf("ParseLevel returned error: %v", err)
+	}
+	if level != LevelDebug {
+		t.Errorf("Expected LevelDebug, got: %d", level)
+	}
+
+	level, err = ParseLevel("Err")
+	if err != nil || level != LevelError {
+		t.Errorf("Expected LevelError for case-insensitive alias, got: %d (%v)", level, err)
+	}
+}
+
+func TestLevelMessageColors(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(true)
+
+	// Message colors are derived from the level alone
+	tests := []struct {
+		name     string
+		logFunc  func(string, ...Field)
+		expected string
+	}{
+		{"Info", logger.Info, Colorize("msg", BrightWhite)},
+		{"Success", logger.Success, Colorize("msg", BrightGreen)},
+		{"Debug", logger.Debug, Colorize("msg", BrightMagenta)},
+		{"Warn", logger.Warn, Colorize("msg", BrightYellow)},
+		{"Error", logger.Error, Colorize("msg", BrightRed)},
+		{"Critical", logger.Critical, Colorize("msg", BrightRed, BgBlack)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger.SetOutput(&buf)
+			tt.logFunc("msg")
+
+			if !strings.Contains(buf.String(), tt.expected) {
+				t.Errorf("Expected message colored as %q, got: %q", tt.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestLogWithFields(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false) // Disable colors for easier testing
+
+	fields := []Field{
+		{Key: "user_id", Value: 123},
+		{Key: "username", Value: "testuser"},
+		{Key: "active", Value: true},
+	}
+
+	output := captureOutput(func() {
+		logger.Info("test message with fields", fields...)
+	})
+
+	// Check that the main message is present
+	if !strings.Contains(output, "test message with fields") {
+		t.Error("Expected output to contain the main message")
+	}
+
+	// Check that Fields: header is present
+	if !strings.Contains(output, "Fields:") {
+		t.Error("Expected output to contain 'Fields:' header")
+	}
+
+	// Check that field values are present in JSON format
+	if !strings.Contains(output, "user_id") {
+		t.Error("Expected output to contain 'user_id' field")
+	}
+
+	if !strings.Contains(output, "testuser") {
+		t.Error("Expected output to contain 'testuser' value")
+	}
+}
+
+func TestWithFields(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+
+	requestLogger := logger.WithFields(
+		Field{Key: "request_id", Value: "req-42"},
+		Field{Key: "user_id", Value: 1},
+	)
+	requestLogger.Info("handling request",
+		Field{Key: "path", Value: "/api/v1/users"},
+		Field{Key: "user_id", Value: 2},
+	)
+
+	output := buf.String()
+	for _, want := range []string{`"request_id": "req-42"`, `"path": "/api/v1/users"`, `"user_id": 2`} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %s, got: %s", want, output)
+		}
+	}
+
+	if strings.Contains(output, `"user_id": 1`) {
+		t.Errorf("Expected call-site field to override base field, got: %s", output)
+	}
+
+	// Base fields come before call-site fields
+	if strings.Index(output, "request_id") > strings.Index(output, "path") {
+		t.Errorf("Expected base fields before call-site fields, got: %s", output)
+	}
+
+	// The parent logger must not be affected
+	buf.Reset()
+	logger.Info("parent message")
+	if strings.Contains(buf.String(), "request_id") {
+		t.Errorf("Expected parent logger to have no base fields, got: %s", buf.String())
+	}
+}
+
+func TestAddField(t *testing.T) {
+	logger := NewLogger()
+	logger.SetFormat(FormatJSON)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+
+	child := logger.WithField("component", "db")
+	logger.AddField("service", "billing")
+	logger.AddField("region", "eu")
+	logger.Info("started")
+
+	output := buf.String()
+	if !strings.Contains(output, `"service":"billing","region":"eu"`) {
+		t.Errorf("Expected added fields in order, got: %s", output)
+	}
+
+	// Loggers derived earlier keep their own fields
+	buf.Reset()
+	child.Info("child")
+	if strings.Contains(buf.String(), "service") || !strings.Contains(buf.String(), `"component":"db"`) {
+		t.Errorf("Expected the derived logger to be unaffected, got: %s", buf.String())
+	}
+
+	// Adding to a derived logger does not reach its siblings
+	sibling := child.WithField("pool", 1)
+	child.AddField("replica", true)
+	buf.Reset()
+	sibling.Info("sibling")
+	if strings.Contains(buf.String(), "replica") {
+		t.Errorf("Expected siblings to be unaffected, got: %s", buf.String())
+	}
+}
+
+func TestRemoveField(t *testing.T) {
+	logger := NewLogger()
+	logger.SetFormat(FormatJSON)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+
+	logger.AddField("request_id", "req-42")
+	logger.AddField("user_id", 7)
+	child := logger.WithField("step", "auth")
+
+	logger.RemoveField("request_id")
+	logger.RemoveField("missing")
+	logger.Info("done")
+
+	output := buf.String()
+	if strings.Contains(output, "request_id") || !strings.Contains(output, `"user_id":7`) {
+		t.Errorf("Expected only user_id to remain, got: %s", output)
+	}
+
+	// Loggers sharing the fields keep them
+	buf.Reset()
+	child.Info("child")
+	if !strings.Contains(buf.String(), `"request_id":"req-42","user_id":7,"step":"auth"`) {
+		t.Errorf("Expected the derived logger to keep its fields, got: %s", buf.String())
+	}
+}
+
+func TestClone(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+	logger.SetLevel(LevelInfo)
+	logger.SetMaxPerSecond(LevelInfo, 1)
+	logger.DisableLevel(LevelSuccess)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+	logger.Info("original")
+
+	clone := logger.Clone()
+	clone.SetColorsEnabled(true)
+	clone.SetLevel(LevelDebug)
+	clone.EnableLevel(LevelSuccess)
+
+	if logger.ColorsEnabled() {
+		t.Error("Expected the original to keep colors disabled")
+	}
+	if logger.Level() != LevelInfo {
+		t.Errorf("Expected the original to keep LevelInfo, got: %s", logger.Level())
+	}
+
+	// The clone has its own rate limit window and counters
+	var cloneBuf bytes.Buffer
+	clone.SetOutput(&cloneBuf)
+	clone.Info("clone")
+	clone.Success("clone success")
+	if !strings.Contains(cloneBuf.String(), "clone") || !strings.Contains(cloneBuf.String(), "\033[") {
+		t.Errorf("Expected a colored record from the clone, got: %q", cloneBuf.String())
+	}
+	if !strings.Contains(cloneBuf.String(), "clone success") {
+		t.Errorf("Expected Success to be enabled on the clone, got: %q", cloneBuf.String())
+	}
+	if got := logger.Counts()[LevelInfo]; got != 1 {
+		t.Errorf("Expected the original to count 1 record, got: %d", got)
+	}
+
+	buf.Reset()
+	logger.Success("still disabled")
+	if buf.Len() != 0 {
+		t.Errorf("Expected Success to stay disabled on the original, got: %q", buf.String())
+	}
+}
+
+func TestEntry(t *testing.T) {
+	logger := NewLogger()
+	logger.SetFormat(FormatJSON)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+
+	entry := logger.WithField("service", "auth").Entry().
+		Str("user", "bob").
+		Int("id", 5).
+		Bool("admin", false).
+		Float64("score", 0.5).
+		Err(errors.New("expired")).
+		Any("tags", []string{"a"}).
+		Fields(String("ip", "10.0.0.1"))
+	_, _, line, _ := runtime.Caller(0)
+	entry.Warn("logged in")
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Expected valid JSON output, got: %s (%v)", buf.String(), err)
+	}
+	if record["msg"] != "logged in" || record["level"] != "warning" {
+		t.Errorf("Expected the message at the warn level, got: %s", buf.String())
+	}
+	if !strings.Contains(buf.String(), `"service":"auth","user":"bob","id":5,"admin":false,"score":0.5,"error":"expired","tags":["a"],"ip":"10.0.0.1"`) {
+		t.Errorf("Expected the entry's fields in order, got: %s", buf.String())
+	}
+
+	// The caller is the line of the level method call
+	if want := fmt.Sprintf("maklogger_test.go:%d", line+1); record["caller"] != want {
+		t.Errorf("Expected caller %s, got: %v", want, record["caller"])
+	}
+
+	buf.Reset()
+	logger.SetLevel(LevelError)
+	logger.Entry().Str("user", "bob").Info("filtered")
+	if buf.Len() != 0 {
+		t.Errorf("Expected entries below the level to be dropped, got: %s", buf.String())
+	}
+}
+
+func TestWithField(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+	logger.SetFieldStyle(FieldStyleInline)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+
+	logger.WithField("a", 1).WithField("b", 2).Info("chained")
+	if !strings.Contains(buf.String(), `{"a":1,"b":2}`) {
+		t.Errorf("Expected both fields, got: %q", buf.String())
+	}
+}
+
+type testStatus int
+
+const (
+	statusInactive testStatus = iota
+	statusActive
+)
+
+func (s testStatus) String() string {
+	switch s {
+	case statusInactive:
+		return "Inactive"
+	case statusActive:
+		return "Active"
+	}
+	return "Unknown"
+}
+
+func TestEnumStrings(t *testing.T) {
+	logger := NewLogger()
+	fields := []Field{{Key: "status", Value: statusActive}}
+
+	// By default the numeric value is logged
+	result := logger.formatFieldsAsJSON(fields)
+	if !strings.Contains(result, `"status": 1`) {
+		t.Errorf("Expected raw numeric value by default, got: %s", result)
+	}
+
+	logger.SetEnumStrings(true)
+	result = logger.formatFieldsAsJSON(fields)
+	if !strings.Contains(result, `"status": "Active(1)"`) {
+		t.Errorf("Expected combined enum representation, got: %s", result)
+	}
+
+	// Plain integers are not affected
+	result = logger.formatFieldsAsJSON([]Field{{Key: "count", Value: 5}})
+	if !strings.Contains(result, `"count": 5`) {
+		t.Errorf("Expected plain integer to stay numeric, got: %s", result)
+	}
+}
+
+func TestNumberGrouping(t *testing.T) {
+	logger := NewLogger()
+	logger.SetNumberGrouping(true)
+
+	fields := []Field{
+		{Key: "bytes", Value: 1234567},
+		{Key: "small", Value: 999},
+		{Key: "balance", Value: -1234567.89},
+		{Key: "ratio", Value: float32(3.14)},
+	}
+
+	result := logger.formatFieldsAsJSON(fields)
+	for _, want := range []string{`"bytes": "1,234,567"`, `"small": "999"`, `"balance": "-1,234,567.89"`, `"ratio": "3.14"`} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected text output to contain %s, got: %s", want, result)
+		}
+	}
+
+	// JSON output keeps raw numbers
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+	logger.SetFormat(FormatJSON)
+	logger.Info("grouping test", fields...)
+
+	if !strings.Contains(buf.String(), `"bytes":1234567`) {
+		t.Errorf("Expected bare number in JSON output, got: %s", buf.String())
+	}
+}
+
+func TestSetName(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+	logger.SetName("http-server")
+
+	if logger.Name() != "http-server" {
+		t.Errorf("Expected name 'http-server', got: %s", logger.Name())
+	}
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+	logger.Info("named message")
+
+	if !strings.Contains(buf.String(), "[http-server]") {
+		t.Errorf("Expected text output to contain the name tag, got: %s", buf.String())
+	}
+
+	// Derived loggers inherit the name and can extend it independently
+	child := logger.WithFields(Field{Key: "request_id", Value: "req-42"})
+	if child.Name() != "http-server" {
+		t.Errorf("Expected child to inherit name, got: %s", child.Name())
+	}
+
+	child.SetName(child.Name() + ".handler")
+	if logger.Name() != "http-server" {
+		t.Errorf("Expected parent name to be unchanged, got: %s", logger.Name())
+	}
+
+	buf.Reset()
+	child.SetFormat(FormatJSON)
+	child.Info("child message")
+
+	if !strings.Contains(buf.String(), `"logger":"http-server.handler"`) {
+		t.Errorf("Expected JSON output to contain the logger name, got: %s", buf.String())
+	}
+}
+
+func TestTypedFieldConstructors(t *testing.T) {
+	err := errors.New("connection timeout")
+
+	tests := []struct {
+		name     string
+		field    Field
+		expected Field
+	}{
+		{"String", String("user", "bob"), Field{Key: "user", Value: "bob"}},
+		{"Int", Int("id", 5), Field{Key: "id", Value: 5}},
+		{"Bool", Bool("active", true), Field{Key: "active", Value: true}},
+		{"Float64", Float64("ratio", 0.75), Field{Key: "ratio", Value: 0.75}},
+		{"Err", Err(err), Field{Key: "error", Value: err}},
+	}
+
+	logger := NewLogger()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.field != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, tt.field)
+			}
+
+			got := logger.formatFieldsAsJSON([]Field{tt.field})
+			want := logger.formatFieldsAsJSON([]Field{tt.expected})
+			if got != want {
+				t.Errorf("Expected identical output to the struct literal, got %s and %s", got, want)
+			}
+		})
+	}
+}
+
+func TestRawJSON(t *testing.T) {
+	payload := []byte(`{"order":{"id":42,"items":["a","b"]}}`)
+
+	logger := NewLogger()
+	result := logger.formatFieldsAsJSON([]Field{RawJSON("payload", payload)})
+
+	if strings.Contains(result, `\"`) {
+		t.Errorf("Expected raw JSON without escaped quotes, got: %s", result)
+	}
+	if !strings.Contains(result, `"payload": {`) || !strings.Contains(result, `"id": 42`) {
+		t.Errorf("Expected payload nested as an object, got: %s", result)
+	}
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+	logger.SetFormat(FormatJSON)
+	logger.Info("raw json", RawJSON("payload", payload))
+
+	var record struct {
+		Payload struct {
+			Order struct {
+				ID int `json:"id"`
+			} `json:"order"`
+		} `json:"payload"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil || record.Payload.Order.ID != 42 {
+		t.Errorf("Expected nested payload in JSON output, got: %s (%v)", buf.String(), err)
+	}
+
+	// Invalid JSON falls back to a string
+	field := RawJSON("payload", []byte("{not json"))
+	if field.Value != "{not json" {
+		t.Errorf("Expected invalid JSON to be logged as a string, got: %#v", field.Value)
+	}
+}
+
+func TestRawJSONValues(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+
+	fields := []Field{
+		{Key: "raw", Value: json.RawMessage(`{"id":42}`)},
+		{Key: "bytes", Value: []byte(` [1, 2]`)},
+		{Key: "binary", Value: []byte("hi")},
+		{Key: "invalid", Value: json.RawMessage(`{oops`)},
+	}
+	logger.Info("payloads", fields...)
+
+	output := buf.String()
+	for _, want := range []string{`"raw": {`, `"id": 42`, `"bytes": [`, `"binary": "aGk="`, `"invalid": "{oops"`} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected text output to contain %s, got: %s", want, output)
+		}
+	}
+	if strings.Contains(output, `\"id\"`) {
+		t.Errorf("Expected raw JSON without escaped quotes, got: %s", output)
+	}
+
+	buf.Reset()
+	logger.SetFormat(FormatJSON)
+	logger.Info("payloads", fields...)
+
+	var record struct {
+		Raw     map[string]int `json:"raw"`
+		Bytes   []int          `json:"bytes"`
+		Binary  []byte         `json:"binary"`
+		Invalid string         `json:"invalid"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Expected valid JSON output, got: %s (%v)", buf.String(), err)
+	}
+	if record.Raw["id"] != 42 || len(record.Bytes) != 2 || string(record.Binary) != "hi" || record.Invalid != "{oops" {
+		t.Errorf("Unexpected values in JSON output: %+v", record)
+	}
+}
+
+func TestSetByteSliceStyle(t *testing.T) {
+	data := []byte("hi\x00\xff")
+	tests := []struct {
+		style ByteSliceStyle
+		want  string
+	}{
+		{ByteSliceBase64, `"data":"aGkA/w=="`},
+		{ByteSliceString, `"data":"hi\u0000` + "\uFFFD" + `"`},
+		{ByteSliceHex, `"data":"686900ff"`},
+	}
+
+	for _, tt := range tests {
+		logger := NewLogger()
+		logger.SetFormat(FormatJSON)
+
+		var buf bytes.Buffer
+		logger.SetOutput(&buf)
+		logger.SetByteSliceStyle(tt.style)
+		logger.Info("packet", Field{Key: "data", Value: data})
+
+		if !strings.Contains(buf.String(), tt.want) {
+			t.Errorf("Style %d: expected %s, got: %s", tt.style, tt.want, buf.String())
+		}
+	}
+
+	// Only the default style embeds byte slices holding JSON
+	jsonTests := []struct {
+		style ByteSliceStyle
+		want  string
+	}{
+		{ByteSliceBase64, `"c":[]`},
+		{ByteSliceString, `"c":"[]"`},
+		{ByteSliceHex, `"c":"5b5d"`},
+		{ByteSliceHex, `"obj":"7b2261223a317d"`},
+	}
+	for _, tt := range jsonTests {
+		logger := NewLogger()
+		logger.SetFormat(FormatJSON)
+
+		var buf bytes.Buffer
+		logger.SetOutput(&buf)
+		logger.SetByteSliceStyle(tt.style)
+		logger.Info("packet", Field{Key: "c", Value: []byte("[]")}, Field{Key: "obj", Value: []byte(`{"a":1}`)})
+
+		if !strings.Contains(buf.String(), tt.want) {
+			t.Errorf("Style %d: expected %s, got: %s", tt.style, tt.want, buf.String())
+		}
+	}
+
+	if NewLogger().ByteSliceStyle() != ByteSliceBase64 {
+		t.Error("Expected base64 to be the default byte slice style")
+	}
+}
+
+func TestSetMaxFieldLength(t *testing.T) {
+	logger := NewLogger()
+	logger.SetFormat(FormatJSON)
+	logger.SetMaxFieldLength(10)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+
+	body := strings.Repeat("x", 50000)
+	logger.Info("response",
+		String("body", body),
+		String("short", "fits"),
+		String("utf8", "aééééééé"), // 15 bytes, the 10th byte is inside a character
+		Group("nested", String("body", body)),
+		Int("count", 1234567890123),
+	)
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Expected valid JSON output, got: %s (%v)", buf.String(), err)
+	}
+
+	if want := "xxxxxxxxxx…(truncated, 50000 bytes)"; record["body"] != want {
+		t.Errorf("Expected body %q, got: %q", want, record["body"])
+	}
+	if record["short"] != "fits" {
+		t.Errorf("Expected short values unchanged, got: %q", record["short"])
+	}
+	if want := "aéééé…(truncated, 15 bytes)"; record["utf8"] != want {
+		t.Errorf("Expected cut at a character boundary %q, got: %q", want, record["utf8"])
+	}
+	if nested, _ := record["nested"].(map[string]any); nested["body"] != "xxxxxxxxxx…(truncated, 50000 bytes)" {
+		t.Errorf("Expected values in groups to be truncated, got: %v", record["nested"])
+	}
+	if record["count"] != float64(1234567890123) {
+		t.Errorf("Expected numbers unchanged, got: %v", record["count"])
+	}
+}
+
+func TestFieldTypes(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+
+	// Test various field types
+	fields := []Field{
+		{Key: "string_field", Value: "test string"},
+		{Key: "int_field", Value: 42},
+		{Key: "float_field", Value: 3.14},
+		{Key: "bool_field", Value: true},
+		{Key: "nil_field", Value: nil},
+	}
+
+	output := captureOutput(func() {
+		logger.Info("testing field types", fields...)
+	})
+
+	// Verify different types are handled correctly
+	expectedValues := []string{"test string", "42", "3.14", "true", "null"}
+	for _, expected := range expectedValues {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain '%s', got: %s", expected, output)
+		}
+	}
+}
+
+func TestColorize(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		fg       Color
+		bg       []Color
+		expected string
+	}{
+		{
+			name:     "foreground only",
+			text:     "test",
+			fg:       Red,
+			expected: "\033[31mtest\033[0m",
+		},
+		{
+			name:     "foreground and background",
+			text:     "test",
+			fg:       Red,
+			bg:       []Color{BgBlue},
+			expected: "\033[31m\033[44mtest\033[0m",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Colorize(tt.text, tt.fg, tt.bg...)
+			if result != tt.expected {
+				t.Errorf("Expected '%s', got '%s'", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestColorizeMulti(t *testing.T) {
+	got := ColorizeMulti("x", Bold, Underline, Red)
+	want := "\033[1m\033[4m\033[31mx\033[0m"
+	if got != want {
+		t.Errorf("ColorizeMulti(\"x\", Bold, Underline, Red) = %q, want %q", got, want)
+	}
+
+	if got := ColorizeMulti("x"); got != "x" {
+		t.Errorf("Expected plain text without attributes, got %q", got)
+	}
+}
+
+func TestHyperlink(t *testing.T) {
+	got := Hyperlink("trace", "https://example.com/trace/42")
+	want := "\033]8;;https://example.com/trace/42\033\\trace\033]8;;\033\\"
+	if got != want {
+		t.Errorf("Hyperlink() = %q, want %q", got, want)
+	}
+
+	if got := HyperlinkIfEnabled("trace", "https://example.com", true); got != Hyperlink("trace", "https://example.com") {
+		t.Errorf("Expected a hyperlink when enabled, got %q", got)
+	}
+	if got := HyperlinkIfEnabled("trace", "https://example.com", false); got != "trace" {
+		t.Errorf("Expected plain text when disabled, got %q", got)
+	}
+}
+
+func TestStripColors(t *testing.T) {
+	colored := Colorize("error", Red, BgWhite) + " " + ColorizeMulti("bold", Bold, RGB(1, 2, 3)) +
+		" " + Hyperlink("trace", "https://example.com") + " \033]0;title\007done"
+	if got, want := StripColors(colored), "error bold trace done"; got != want {
+		t.Errorf("StripColors() = %q, want %q", got, want)
+	}
+
+	logger := NewLogger()
+	logger.SetColorsEnabled(true)
+	var colorBuf, plainBuf bytes.Buffer
+	logger.SetOutput(&colorBuf)
+	logger.SetClock(func() time.Time { return time.Unix(0, 0) })
+	logger.SetCallerEnabled(false)
+	logger.Info("same text", Int("n", 1))
+	logger.SetColorsEnabled(false)
+	logger.SetOutput(&plainBuf)
+	logger.Info("same text", Int("n", 1))
+
+	stripped := StripColors(colorBuf.String())
+	if strings.Contains(stripped, "\033") {
+		t.Errorf("Expected no escape codes left, got %q", stripped)
+	}
+	if stripped != plainBuf.String() {
+		t.Errorf("Expected stripped output to equal plain output\ngot:  %q\nwant: %q", stripped, plainBuf.String())
+	}
+}
+
+func TestRGB(t *testing.T) {
+	if got, want := RGB(255, 128, 0), Color("\033[38;2;255;128;0m"); got != want {
+		t.Errorf("RGB(255, 128, 0) = %q, want %q", got, want)
+	}
+	if got, want := BgRGB(0, 0, 0), Color("\033[48;2;0;0;0m"); got != want {
+		t.Errorf("BgRGB(0, 0, 0) = %q, want %q", got, want)
+	}
+
+	got := Colorize("x", RGB(255, 128, 0), BgRGB(1, 2, 3))
+	want := "\033[38;2;255;128;0m\033[48;2;1;2;3mx" + string(Reset)
+	if got != want {
+		t.Errorf("Colorize with RGB = %q, want %q", got, want)
+	}
+}
+
+func TestColor256(t *testing.T) {
+	if got, want := Color256(208), Color("\033[38;5;208m"); got != want {
+		t.Errorf("Color256(208) = %q, want %q", got, want)
+	}
+	if got, want := BgColor256(17), Color("\033[48;5;17m"); got != want {
+		t.Errorf("BgColor256(17) = %q, want %q", got, want)
+	}
+
+	got := Colorize("x", Color256(208), BgColor256(17))
+	want := "\033[38;5;208m\033[48;5;17mx" + string(Reset)
+	if got != want {
+		t.Errorf("Colorize with Color256 = %q, want %q", got, want)
+	}
+}
+
+func TestColorizeIfEnabled(t *testing.T) {
+	text := "test"
+	colored := ColorizeIfEnabled(text, true, Red)
+	uncolored := ColorizeIfEnabled(text, false, Red)
+
+	// When enabled, should return colored text
+	if colored == text {
+		t.Error("Expected colored text when colors are enabled")
+	}
+
+	// When disabled, should return plain text
+	if uncolored != text {
+		t.Error("Expected plain text when colors are disabled")
+	}
+}
+
+func TestFormatFieldsAsJSON(t *testing.T) {
+	logger := NewLogger()
+
+	// Test empty fields
+	result := logger.formatFieldsAsJSON([]Field{})
+	if result != "" {
+		t.Error("Expected empty string for no fields")
+	}
+
+	// Test single field
+	fields := []Field{
+		{Key: "test_key", Value: "test_value"},
+	}
+	result = logger.formatFieldsAsJSON(fields)
+
+	if !strings.Contains(result, "test_key") {
+		t.Error("Expected result to contain field key")
+	}
+
+	if !strings.Contains(result, "test_value") {
+		t.Error("Expected result to contain field value")
+	}
+
+	// Check JSON formatting with proper indentation
+	if !strings.Contains(result, "  {") {
+		t.Error("Expected proper JSON indentation")
+	}
+}
+
+func TestFieldOrder(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+
+	logger.Info("ordered fields",
+		Field{Key: "user_id", Value: 123},
+		Field{Key: "username", Value: "john_doe"},
+		Field{Key: "ip_address", Value: "192.168.1.100"},
+	)
+
+	output := buf.String()
+	last := -1
+	for _, key := range []string{`"user_id"`, `"username"`, `"ip_address"`} {
+		offset := strings.Index(output, key)
+		if offset <= last {
+			t.Fatalf("Expected %s to appear after the previous key, got: %s", key, output)
+		}
+		last = offset
+	}
+}
+
+func TestSetFieldStyle(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+
+	if logger.FieldStyle() != FieldStylePretty {
+		t.Error("Field style should be pretty by default")
+	}
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+	logger.SetFieldStyle(FieldStyleInline)
+	logger.Info("inline fields", String("user", "bob"), Int("attempt", 2))
+
+	output := buf.String()
+	if strings.Count(output, "\n") != 1 || !strings.HasSuffix(output, "\n") {
+		t.Errorf("Expected a single line record, got: %q", output)
+	}
+	if !strings.Contains(output, `inline fields {"user":"bob","attempt":2}`) {
+		t.Errorf("Expected compact fields after the message, got: %q", output)
+	}
+	if strings.Contains(output, "Fields:") {
+		t.Errorf("Expected no fields header in inline mode, got: %q", output)
+	}
+}
+
+func TestSetFieldTrailingNewline(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+
+	logger.Info("with fields", Int("n", 1))
+	logger.Info("next")
+	if strings.Contains(buf.String(), "\n\n") {
+		t.Errorf("Expected no blank line after the fields block by default, got: %q", buf.String())
+	}
+
+	buf.Reset()
+	logger.SetFieldTrailingNewline(true)
+	logger.Info("with fields", Int("n", 1))
+	logger.Info("next")
+	if !strings.Contains(buf.String(), "    }\n\n") || strings.Count(buf.String(), "\n\n") != 1 {
+		t.Errorf("Expected a blank line after the fields block only, got: %q", buf.String())
+	}
+
+	// Inline fields stay on the record line
+	buf.Reset()
+	logger.SetFieldStyle(FieldStyleInline)
+	logger.Info("inline", Int("n", 1))
+	if strings.Contains(buf.String(), "\n\n") {
+		t.Errorf("Expected no blank line after inline fields, got: %q", buf.String())
+	}
+}
+
+func TestSetSortFields(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+	logger.SetFieldStyle(FieldStyleInline)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+
+	fields := []Field{String("zone", "eu"), Int("attempt", 2), String("method", "GET")}
+	logger.Info("unsorted", fields...)
+	if !strings.Contains(buf.String(), `{"zone":"eu","attempt":2,"method":"GET"}`) {
+		t.Errorf("Expected insertion order by default, got: %q", buf.String())
+	}
+
+	buf.Reset()
+	logger.SetSortFields(true)
+	logger.Info("sorted", fields...)
+	if !strings.Contains(buf.String(), `{"attempt":2,"method":"GET","zone":"eu"}`) {
+		t.Errorf("Expected fields sorted by key, got: %q", buf.String())
+	}
+
+	// Reserved keys stay first in JSON output
+	buf.Reset()
+	logger.SetFormat(FormatJSON)
+	logger.SetCallerEnabled(false)
+	logger.Info("sorted", fields...)
+	if !strings.Contains(buf.String(), `"msg":"sorted","attempt":2,"method":"GET","zone":"eu"}`) {
+		t.Errorf("Expected sorted fields after the reserved keys, got: %q", buf.String())
+	}
+}
+
+func TestSetDuplicateKeyPolicy(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+	logger.SetFieldStyle(FieldStyleInline)
+
+	child := logger.WithFields(Int("user_id", 1), String("service", "api"))
+
+	tests := []struct {
+		policy DuplicateKeyPolicy
+		want   string
+	}{
+		{DuplicateKeepLast, `{"user_id":2,"service":"api"}`},
+		{DuplicateKeepFirst, `{"user_id":1,"service":"api"}`},
+		{DuplicateRename, `{"user_id":1,"service":"api","user_id#2":2}`},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		child.SetOutput(&buf)
+		child.SetDuplicateKeyPolicy(tt.policy)
+		child.Info("duplicate", Int("user_id", 2))
+
+		if !strings.Contains(buf.String(), tt.want) {
+			t.Errorf("Policy %d: expected %s, got: %q", tt.policy, tt.want, buf.String())
+		}
+	}
+}
+
+func TestSetFieldsBaseIndent(t *testing.T) {
+	logger := NewLogger()
+	fields := []Field{{Key: "test_key", Value: "test_value"}}
+
+	if logger.FieldsBaseIndent() != 2 {
+		t.Errorf("Expected default base indent of 2, got: %d", logger.FieldsBaseIndent())
+	}
+
+	for _, n := range []int{0, 2, 6} {
+		logger.SetFieldsBaseIndent(n)
+		result := logger.formatFieldsAsJSON(fields)
+		lines := strings.Split(result, "\n")
+
+		// The opening brace carries only the base indentation
+		if want := strings.Repeat(" ", n) + "{"; lines[0] != want {
+			t.Errorf("Base indent %d: expected first line %q, got: %q", n, want, lines[0])
+		}
+	}
+}
+
+func TestSetCallerEnabled(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+
+	if !logger.CallerEnabled() {
+		t.Error("Caller info should be enabled by default")
+	}
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+	logger.SetCallerEnabled(false)
+	logger.Info("no caller")
+
+	output := buf.String()
+	if strings.Contains(output, "maklogger_test.go") || strings.Contains(output, "📁") {
+		t.Errorf("Expected no caller info, got: %s", output)
+	}
+	if !strings.Contains(output, "INFO") || !strings.Contains(output, "no caller") {
+		t.Errorf("Expected level and message, got: %s", output)
+	}
+
+	buf.Reset()
+	logger.SetFormat(FormatJSON)
+	logger.Info("no caller")
+	if strings.Contains(buf.String(), `"caller"`) {
+		t.Errorf("Expected no caller key in JSON, got: %s", buf.String())
+	}
+}
+
+// logViaHelper wraps the logger like a user's helper function would.
+func logViaHelper(logger *MakLogger, msg string) {
+	logger.Info(msg)
+}
+
+func TestSetCallerSkip(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+	logger.SetFormat(FormatJSON)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+
+	// Without a skip the helper is reported
+	logViaHelper(logger, "through helper")
+	var record map[string]any
+	json.Unmarshal(buf.Bytes(), &record)
+	helperCaller := record["caller"].(string)
+
+	// With skip=1 the helper's caller, this test, is reported
+	buf.Reset()
+	logger.SetCallerSkip(1)
+	_, _, line, _ := runtime.Caller(0)
+	logViaHelper(logger, "through helper") // must stay on the line after runtime.Caller
+
+	record = nil
+	json.Unmarshal(buf.Bytes(), &record)
+	want := fmt.Sprintf("maklogger_test.go:%d", line+1)
+	if record["caller"] != want {
+		t.Errorf("Expected caller %s, got: %v (helper reported as %s)", want, record["caller"], helperCaller)
+	}
+	if helperCaller == want {
+		t.Errorf("Expected helper line without skip, got the test line %s", helperCaller)
+	}
+}
+
+func TestSetCallerFullPath(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+	logger.SetIconsEnabled(false)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+
+	_, thisFile, _, _ := runtime.Caller(0)
+
+	logger.Info("base name")
+	if !strings.Contains(buf.String(), "│ maklogger_test.go:") {
+		t.Errorf("Expected the base name by default, got: %q", buf.String())
+	}
+
+	buf.Reset()
+	logger.SetCallerFullPath(true)
+	logger.Info("full path")
+	if !strings.Contains(buf.String(), "│ "+thisFile+":") {
+		t.Errorf("Expected the full path %s, got: %q", thisFile, buf.String())
+	}
+
+	buf.Reset()
+	logger.SetCallerTrimPrefix(filepath.Dir(filepath.Dir(thisFile)))
+	logger.Info("trimmed path")
+	want := filepath.Base(filepath.Dir(thisFile)) + "/maklogger_test.go:"
+	if !strings.Contains(buf.String(), "│ "+want) {
+		t.Errorf("Expected the trimmed path %s, got: %q", want, buf.String())
+	}
+}
+
+// callerFuncType has a method that logs, for caller function name tests.
+type callerFuncType struct {
+	logger *MakLogger
+}
+
+func (c *callerFuncType) Handle() {
+	c.logger.Info("handled")
+}
+
+func TestSetCallerFullFunc(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+	logger.SetIconsEnabled(false)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+	handler := &callerFuncType{logger: logger}
+
+	handler.Handle()
+	if !strings.Contains(buf.String(), " Handle │ ") {
+		t.Errorf("Expected the short function name by default, got: %q", buf.String())
+	}
+
+	buf.Reset()
+	logger.SetCallerFullFunc(true)
+	handler.Handle()
+	if !strings.Contains(buf.String(), " github.com/makhkets/maklogger.(*callerFuncType).Handle │ ") {
+		t.Errorf("Expected the full function name, got: %q", buf.String())
+	}
+}
+
+func TestGetCallerInfo(t *testing.T) {
+	file, line, function := getCallerInfo(0)
+
+	// Should not return default values for valid caller
+	if file == "???" || line == 0 || function == "???" {
+		t.Error("getCallerInfo should return valid caller information")
+	}
+
+	// File should be the test file
+	if !strings.Contains(file, "test.go") {
+		t.Errorf("Expected file to contain 'test.go', got: %s", file)
+	}
+
+	// Function should contain test function name
+	if !strings.Contains(function, "Test") {
+		t.Errorf("Expected function to contain 'Test', got: %s", function)
+	}
+
+	// Line should be positive
+	if line <= 0 {
+		t.Errorf("Expected positive line number, got: %d", line)
+	}
+}
+
+func TestLogTimestamp(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+
+	output := captureOutput(func() {
+		logger.Info("timestamp test")
+	})
+
+	// Check that output contains timestamp-like format (YYYY-MM-DD HH:MM:SS.mmm)
+	if !strings.Contains(output, time.Now().Format("2006-")) {
+		t.Error("Expected output to contain year")
+	}
+
+	// Check for time separator
+	if !strings.Contains(output, ":") {
+		t.Error("Expected output to contain time separators")
+	}
+}
+
+func TestNewTestLogger(t *testing.T) {
+	logger, buf := NewTestLogger()
+
+	if logger.ColorsEnabled() {
+		t.Error("Expected colors to be disabled")
+	}
+
+	// Code under test logs through the returned logger
+	handle := func(logger *MakLogger) {
+		logger.Info("order placed", Int("order_id", 7))
+	}
+	handle(logger)
+
+	output := buf.String()
+	if !strings.Contains(output, "INFO") || !strings.Contains(output, "order placed") || !strings.Contains(output, `"order_id": 7`) {
+		t.Errorf("Expected the record in the buffer, got: %s", output)
+	}
+	if strings.Contains(output, "\033[") {
+		t.Errorf("Expected no color codes, got: %q", output)
+	}
+}
+
+func TestSetTimeFormat(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+
+	if logger.TimeFormat() != DefaultTimeFormat {
+		t.Errorf("Expected default time format %q, got: %q", DefaultTimeFormat, logger.TimeFormat())
+	}
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+	logger.SetTimeFormat(time.RFC3339)
+	logger.Info("rfc3339 test")
+
+	timestamp := strings.TrimSpace(strings.TrimPrefix(strings.Split(buf.String(), "│")[0], "🕒"))
+	if _, err := time.Parse(time.RFC3339, timestamp); err != nil {
+		t.Errorf("Expected RFC3339 timestamp, got %q: %v", timestamp, err)
+	}
+
+	// An empty layout suppresses the timestamp
+	buf.Reset()
+	logger.SetTimeFormat("")
+	logger.Info("no timestamp")
+
+	if strings.Contains(buf.String(), "🕒") || strings.Contains(buf.String(), time.Now().Format("2006-")) {
+		t.Errorf("Expected no timestamp, got: %s", buf.String())
+	}
+	if !strings.Contains(buf.String(), "no timestamp") {
+		t.Errorf("Expected message to be logged, got: %s", buf.String())
+	}
+}
+
+func TestSetSeparator(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+	logger.SetIconsEnabled(false)
+
+	if logger.Separator() != DefaultSeparator {
+		t.Errorf("Expected default separator %q, got: %q", DefaultSeparator, logger.Separator())
+	}
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+	logger.SetSeparator(" | ")
+	logger.Info("separated")
+
+	output := buf.String()
+	if strings.Contains(output, "│") {
+		t.Errorf("Expected the default separator to be replaced, got: %s", output)
+	}
+	if !strings.Contains(output, " | INFO") || !strings.HasSuffix(output, " | separated\n") {
+		t.Errorf("Expected segments separated by \" | \", got: %q", output)
+	}
+}
+
+func TestSetLineEnding(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+
+	if logger.LineEnding() != "\n" {
+		t.Errorf("Expected default line ending \"\\n\", got: %q", logger.LineEnding())
+	}
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+	logger.SetLineEnding("\r\n")
+
+	onlyCRLF := func(output string) bool {
+		return strings.HasSuffix(output, "\r\n") && strings.Count(output, "\n") == strings.Count(output, "\r\n")
+	}
+
+	logger.Info("text", Int("n", 1), Group("g", Bool("ok", true)))
+	if output := buf.String(); !onlyCRLF(output) || strings.Count(output, "\r\n") != 8 {
+		t.Errorf("Expected every line of the record and field block to end with CRLF, got: %q", output)
+	}
+
+	for _, format := range []Format{FormatJSON, FormatLogfmt, FormatSyslog} {
+		buf.Reset()
+		logger.SetFormat(format)
+		logger.Info("structured", Int("n", 1))
+		if output := buf.String(); !onlyCRLF(output) || strings.Count(output, "\r\n") != 1 {
+			t.Errorf("Format %d: expected a single CRLF terminated line, got: %q", format, output)
+		}
+	}
+
+	// Audit trailers use the same ending and the chain still verifies
+	buf.Reset()
+	key := []byte("secret")
+	logger.SetFormat(FormatText)
+	logger.EnableAuditChain(key)
+	logger.Info("audited", Int("n", 1))
+	logger.Warn("audited again")
+	if output := buf.String(); !onlyCRLF(output) {
+		t.Errorf("Expected audit trailers to end with CRLF, got: %q", output)
+	}
+	if err := VerifyAuditChain(key, &buf); err != nil {
+		t.Errorf("Expected the audit chain to verify, got: %v", err)
+	}
+}
+
+func TestErrorCauseChain(t *testing.T) {
+	root := errors.New("permission denied")
+	middle := fmt.Errorf("open config: %w", root)
+	top := fmt.Errorf("load settings: %w", middle)
+
+	logger := NewLogger()
+	logger.SetFormat(FormatJSON)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+	logger.Error("startup failed", Field{Key: "error", Value: top})
+
+	var record struct {
+		Error struct {
+			Message string   `json:"message"`
+			Causes  []string `json:"causes"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Expected valid JSON, got error %v for: %s", err, buf.String())
+	}
+
+	if record.Error.Message != top.Error() {
+		t.Errorf("Expected message %q, got: %q", top.Error(), record.Error.Message)
+	}
+
+	expected := []string{middle.Error(), root.Error()}
+	if strings.Join(record.Error.Causes, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected causes %q, got: %q", expected, record.Error.Causes)
+	}
+
+	// Text output joins the chain
+	result := NewLogger().formatFieldsAsJSON([]Field{{Key: "error", Value: top}})
+	want := top.Error() + " caused by: " + middle.Error() + " caused by: " + root.Error()
+	if !strings.Contains(result, want) {
+		t.Errorf("Expected text output to contain %q, got: %s", want, result)
+	}
+
+	// Plain errors render as their message instead of {}
+	result = NewLogger().formatFieldsAsJSON([]Field{{Key: "error", Value: errors.New("boom")}})
+	if !strings.Contains(result, `"error": "boom"`) {
+		t.Errorf("Expected plain error message, got: %s", result)
+	}
+}
+
+func TestSetErrorCauses(t *testing.T) {
+	root := errors.New("connection refused")
+	top := fmt.Errorf("query users: %w", root)
+
+	logger := NewLogger()
+	logger.SetErrorCauses(true)
+	result := logger.formatFieldsAsJSON([]Field{Err(top)})
+
+	var fields struct {
+		Error struct {
+			Message string   `json:"message"`
+			Causes  []string `json:"causes"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(result), &fields); err != nil {
+		t.Fatalf("Invalid fields JSON %q: %v", result, err)
+	}
+	if fields.Error.Message != top.Error() {
+		t.Errorf("Expected message %q, got %q", top.Error(), fields.Error.Message)
+	}
+	if len(fields.Error.Causes) != 1 || fields.Error.Causes[0] != root.Error() {
+		t.Errorf("Expected causes [%q], got %q", root.Error(), fields.Error.Causes)
+	}
+}
+
+func TestSetUTC(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+	logger.SetTimeFormat(time.RFC3339)
+	logger.SetUTC(true)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+	logger.Info("utc test")
+
+	timestamp := strings.TrimSpace(strings.TrimPrefix(strings.Split(buf.String(), "│")[0], "🕒"))
+	parsed, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		t.Fatalf("Expected RFC3339 timestamp, got %q: %v", timestamp, err)
+	}
+
+	if _, offset := parsed.Zone(); offset != 0 {
+		t.Errorf("Expected zero UTC offset, got %d in %q", offset, timestamp)
+	}
+}
+
+func TestComplexFieldValues(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+
+	// Test complex nested structures
+	complexValue := map[string]interface{}{
+		"nested": map[string]interface{}{
+			"key":    "value",
+			"number": 42,
+		},
+		"array": []string{"item1", "item2"},
+	}
+
+	fields := []Field{
+		{Key: "complex", Value: complexValue},
+	}
+
+	output := captureOutput(func() {
+		logger.Info("complex field test", fields...)
+	})
+
+	// Should contain nested structure elements
+	if !strings.Contains(output, "nested") {
+		t.Error("Expected output to contain nested structure")
+	}
+
+	if !strings.Contains(output, "array") {
+		t.Error("Expected output to contain array field")
+	}
+}
+
+func TestSlowFieldsWarning(t *testing.T) {
+	// An artificially large field value
+	large := make([]string, 200)
+	for i := range large {
+		large[i] = strings.Repeat("x", 16)
+	}
+
+	for _, format := range []Format{FormatText, FormatJSON, FormatLogfmt, FormatSyslog} {
+		logger := NewLogger()
+		logger.SetColorsEnabled(false)
+		logger.SetFormat(format)
+		logger.SetSlowFieldsThreshold(time.Nanosecond)
+
+		var warnings []error
+		logger.OnError(func(err error) {
+			warnings = append(warnings, err)
+		})
+
+		captureOutput(func() {
+			for i := 0; i < 5; i++ {
+				logger.Info("large field test", Field{Key: "payload", Value: large})
+			}
+		})
+
+		// The warning should fire, but only once within the throttle interval
+		if len(warnings) != 1 {
+			t.Fatalf("Format %d: expected exactly 1 slow fields warning, got %d", format, len(warnings))
+		}
+
+		if !strings.Contains(warnings[0].Error(), "reducing field size") {
+			t.Errorf("Format %d: expected warning to suggest reducing field size, got: %v", format, warnings[0])
+		}
+	}
+}
+
+func TestJSONFormat(t *testing.T) {
+	logger := NewLogger()
+	logger.SetFormat(FormatJSON)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+	logger.Info("json message", Field{Key: "user_id", Value: 123})
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Expected valid JSON, got error %v for: %s", err, buf.String())
+	}
+
+	if record["msg"] != "json message" {
+		t.Errorf("Expected msg to be 'json message', got: %v", record["msg"])
+	}
+
+	if record["level"] != "info" {
+		t.Errorf("Expected level to be 'info', got: %v", record["level"])
+	}
+
+	if record["user_id"] != float64(123) {
+		t.Errorf("Expected user_id to be 123, got: %v", record["user_id"])
+	}
+
+	if !strings.Contains(record["caller"].(string), "maklogger_test.go") {
+		t.Errorf("Expected caller to point to the test file, got: %v", record["caller"])
+	}
+}
+
+func TestLogfmtFormat(t *testing.T) {
+	logger := NewLogger()
+	logger.SetFormat(FormatLogfmt)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+	logger.Info("User logged in",
+		Field{Key: "user_id", Value: 12345},
+		Field{Key: "username", Value: "john_doe"},
+		Field{Key: "agent", Value: "Mozilla/5.0 (X11)"},
+		Field{Key: "empty", Value: ""},
+		Field{Key: "active", Value: true},
+	)
+
+	output := buf.String()
+	if strings.Count(output, "\n") != 1 {
+		t.Errorf("Expected a single line, got: %q", output)
+	}
+
+	expected := []string{
+		"time=",
+		"level=info",
+		`msg="User logged in"`,
+		"caller=maklogger_test.go:",
+		"user_id=12345",
+		"username=john_doe",
+		`agent="Mozilla/5.0 (X11)"`,
+		`empty=""`,
+		"active=true",
+	}
+	for _, want := range expected {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %s, got: %s", want, output)
+		}
+	}
+}
+
+func TestSyslogFormat(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(true)
+	logger.SetFormat(FormatSyslog)
+	logger.SetCallerEnabled(false)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+
+	tests := []struct {
+		name    string
+		logFunc func(string, ...Field)
+		prefix  string
+	}{
+		{"Debug", logger.Debug, "<15>1 "},
+		{"Info", logger.Info, "<14>1 "},
+		{"Success", logger.Success, "<13>1 "},
+		{"Warn", logger.Warn, "<12>1 "},
+		{"Error", logger.Error, "<11>1 "},
+		{"Critical", logger.Critical, "<10>1 "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			tt.logFunc("syslog message", String("user", "bob"))
+
+			output := buf.String()
+			if !strings.HasPrefix(output, tt.prefix) {
+				t.Errorf("Expected prefix %q, got: %q", tt.prefix, output)
+			}
+			if strings.Contains(output, "\033[") {
+				t.Errorf("Expected no ANSI escapes, got: %q", output)
+			}
+			if !strings.HasSuffix(output, " - - syslog message user=bob\n") {
+				t.Errorf("Expected the message and fields at the end, got: %q", output)
+			}
+			if header := strings.Fields(output); len(header) < 5 || header[4] != strconv.Itoa(os.Getpid()) {
+				t.Errorf("Expected the process ID in the header, got: %q", output)
+			}
+		})
+	}
+
+	if syslogSeverity(LevelPanic) != 1 || syslogSeverity(LevelFatal) != 0 {
+		t.Errorf("Unexpected severities for Panic and Fatal: %d, %d", syslogSeverity(LevelPanic), syslogSeverity(LevelFatal))
+	}
+}
+
+func TestOTelEmitter(t *testing.T) {
+	logger := NewLogger()
+	logger.SetOutput(io.Discard)
+	logger.SetName("api")
+
+	var records []OTelRecord
+	logger.SetOTelEmitter(OTelEmitterFunc(func(r OTelRecord) {
+		records = append(records, r)
+	}))
+
+	logger.Debug("debug")
+	logger.Info("info", String("user", "bob"))
+	logger.Success("success")
+	logger.Warn("warn")
+	logger.Error("error")
+	logger.Critical("critical")
+
+	wantNumbers := []int{5, 9, 10, 13, 17, 18}
+	if len(records) != len(wantNumbers) {
+		t.Fatalf("Expected %d records, got %d", len(wantNumbers), len(records))
+	}
+	for i, want := range wantNumbers {
+		if records[i].SeverityNumber != want {
+			t.Errorf("Record %q: expected severity %d, got %d", records[i].Body, want, records[i].SeverityNumber)
+		}
+	}
+
+	info := records[1]
+	if info.SeverityText != "INFO" || info.Body != "info" || info.ScopeName != "api" || info.Timestamp.IsZero() {
+		t.Errorf("Unexpected record: %+v", info)
+	}
+	if len(info.Attributes) == 0 || info.Attributes[0] != String("user", "bob") {
+		t.Errorf("Expected the fields as attributes, got: %v", info.Attributes)
+	}
+	if attr := info.Attributes[len(info.Attributes)-3]; attr.Key != "code.filepath" || !filepath.IsAbs(attr.Value.(string)) {
+		t.Errorf("Expected the caller as attributes, got: %v", info.Attributes)
+	}
+
+	if otelSeverity(LevelPanic) != 21 || otelSeverity(LevelFatal) != 22 {
+		t.Errorf("Unexpected severities for Panic and Fatal: %d, %d", otelSeverity(LevelPanic), otelSeverity(LevelFatal))
+	}
+}
+
+func TestLogfmtValue(t *testing.T) {
+	tests := []struct {
+		value    any
+		expected string
+	}{
+		{"plain", "plain"},
+		{"with space", `"with space"`},
+		{"a=b", `"a=b"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{"line\nbreak", `"line\nbreak"`},
+		{"", `""`},
+		{42, "42"},
+		{3.5, "3.5"},
+		{nil, ""},
+		{[]int{1, 2}, "[1,2]"},
+	}
+
+	for _, tt := range tests {
+		if got := logfmtValue(tt.value); got != tt.expected {
+			t.Errorf("logfmtValue(%#v) = %s, expected %s", tt.value, got, tt.expected)
+		}
+	}
+}
+
+func TestJSONKeys(t *testing.T) {
+	logger := NewLogger()
+	logger.SetFormat(FormatJSON)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+
+	logger.SetMessageKey("message")
+	logger.Info("renamed message")
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Expected valid JSON, got error %v for: %s", err, buf.String())
+	}
+
+	if record["message"] != "renamed message" {
+		t.Errorf("Expected message under 'message', got: %s", buf.String())
+	}
+	if _, ok := record["msg"]; ok {
+		t.Errorf("Expected no 'msg' key after renaming, got: %s", buf.String())
+	}
+
+	// Other reserved keys are unchanged
+	for _, key := range []string{"time", "level", "caller"} {
+		if _, ok := record[key]; !ok {
+			t.Errorf("Expected reserved key %q to be unchanged, got: %s", key, buf.String())
+		}
+	}
+
+	logger.SetJSONKeys(JSONKeys{Time: "ts", Level: "severity"})
+	want := JSONKeys{Time: "ts", Level: "severity", Message: "msg", Caller: "caller", Logger: "logger"}
+	if got := logger.JSONKeys(); got != want {
+		t.Errorf("Expected keys %+v, got: %+v", want, got)
+	}
+}
+
+func TestCollisionPolicy(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    CollisionPolicy
+		level     any
+		prefixed  any
+		wantError bool
+	}{
+		{"prefix", CollisionPrefix, "info", "user value", false},
+		{"error", CollisionError, "info", nil, true},
+		{"user wins", CollisionUserWins, "user value", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := NewLogger()
+			logger.SetFormat(FormatJSON)
+			logger.SetCollisionPolicy(tt.policy)
+
+			var reported error
+			logger.OnError(func(err error) {
+				reported = err
+			})
+
+			var buf bytes.Buffer
+			logger.SetOutput(&buf)
+			logger.Info("collision test", Field{Key: "level", Value: "user value"})
+
+			var record map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+				t.Fatalf("Expected valid JSON, got error %v for: %s", err, buf.String())
+			}
+
+			if record["level"] != tt.level {
+				t.Errorf("Expected level %v, got: %v", tt.level, record["level"])
+			}
+
+			if record["fields.level"] != tt.prefixed {
+				t.Errorf("Expected fields.level %v, got: %v", tt.prefixed, record["fields.level"])
+			}
+
+			if (reported != nil) != tt.wantError {
+				t.Errorf("Expected error reported: %v, got: %v", tt.wantError, reported)
+			}
+
+			if strings.Count(buf.String(), `"level"`) != 1 {
+				t.Errorf("Expected exactly one level key, got: %s", buf.String())
+			}
+		})
+	}
+}
+
+func TestChannelFieldValue(t *testing.T) {
+	ch := make(chan int, 5)
+	ch <- 1
+	ch <- 2
+
+	logger := NewLogger()
+	result := logger.formatFieldsAsJSON([]Field{{Key: "queue", Value: ch}})
+
+	if !strings.Contains(result, `"chan(len=2, cap=5)"`) {
+		t.Errorf("Expected channel to render as len/cap, got: %s", result)
+	}
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+	logger.SetFormat(FormatJSON)
+	logger.Info("channel test", Field{Key: "queue", Value: ch})
+
+	if !strings.Contains(buf.String(), `"queue":"chan(len=2, cap=5)"`) {
+		t.Errorf("Expected channel to render as len/cap in JSON, got: %s", buf.String())
+	}
+}
+
+func TestRequestField(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/v1/users?page=2&token=secret123", nil)
+	r.RemoteAddr = "192.168.1.100:54321"
+	r.Header.Set("User-Agent", "test-agent")
+	r.Header.Set("Authorization", "Bearer abc.def")
+
+	field := Request(r, "token")
+	if field.Key != "request" {
+		t.Errorf("Expected key 'request', got: %s", field.Key)
+	}
+
+	logger := NewLogger()
+	result := logger.formatFieldsAsJSON([]Field{field})
+
+	expected := []string{`"GET"`, `"/api/v1/users"`, `"192.168.1.100:54321"`, `"test-agent"`}
+	for _, want := range expected {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected result to contain %s, got: %s", want, result)
+		}
+	}
+
+	// The masked query value is not URL-escaped
+	var decoded struct {
+		Request struct {
+			Query map[string][]string `json:"query"`
+		} `json:"request"`
+	}
+	if err := json.Unmarshal([]byte(result), &decoded); err != nil {
+		t.Fatalf("Expected valid JSON, got %q: %v", result, err)
+	}
+	query := decoded.Request.Query
+	if len(query["page"]) != 1 || query["page"][0] != "2" || len(query["token"]) != 1 || query["token"][0] != "***" {
+		t.Errorf("Expected page=2 and a masked token, got: %v", query)
+	}
+
+	if strings.Contains(result, "secret123") {
+		t.Errorf("Expected token query param to be masked, got: %s", result)
+	}
+
+	if strings.Contains(result, "abc.def") {
+		t.Errorf("Expected Authorization header to be masked, got: %s", result)
+	}
+}
+
+func TestSlogHandler(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+	logger.SetLevel(LevelInfo)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+
+	slogger := slog.New(NewSlogHandler(logger)).
+		With("service", "api").
+		WithGroup("http")
+	slogger.Warn("slow request", "method", "GET", slog.Group("response", "status", 200))
+
+	output := buf.String()
+	expected := []string{"WARNING", "slow request", `"service": "api"`, `"http.method": "GET"`, `"http.response.status": 200`, "maklogger_test.go"}
+	for _, want := range expected {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %s, got: %s", want, output)
+		}
+	}
+
+	// Debug is below the configured level
+	buf.Reset()
+	slogger.Debug("debug message")
+	if buf.Len() != 0 {
+		t.Errorf("Expected debug record to be filtered, got: %s", buf.String())
+	}
+
+	levels := []struct {
+		level    slog.Level
+		expected Level
+	}{
+		{slog.LevelDebug, LevelDebug},
+		{slog.LevelInfo, LevelInfo},
+		{slog.LevelWarn, LevelWarn},
+		{slog.LevelError, LevelError},
+		{slog.LevelError + 4, LevelCritical},
+	}
+	for _, tt := range levels {
+		if got := fromSlogLevel(tt.level); got != tt.expected {
+			t.Errorf("fromSlogLevel(%v) = %d, expected %d", tt.level, got, tt.expected)
+		}
+	}
+}
+
+func TestSamplingKey(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+	logger.SetSampling(2, time.Minute)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+
+	// Different messages sharing one sampling key are limited as a group
+	for i := 0; i < 10; i++ {
+		logger.Error(fmt.Sprintf("db error: connection %d refused", i), SamplingKey("db"))
+	}
+
+	output := buf.String()
+	if count := strings.Count(output, "db error"); count != 2 {
+		t.Errorf("Expected 2 sampled records, got %d: %s", count, output)
+	}
+
+	if strings.Contains(output, "sampling_key") {
+		t.Errorf("Expected sampling key field to be hidden, got: %s", output)
+	}
+
+	// Other groups and unkeyed records are not affected
+	buf.Reset()
+	logger.Error("cache error", SamplingKey("cache"))
+	logger.Error("unkeyed error")
+	logger.Error("unkeyed error")
+	logger.Error("unkeyed error")
+
+	if count := strings.Count(buf.String(), "\n"); count != 4 {
+		t.Errorf("Expected 4 records from other groups, got %d: %s", count, buf.String())
+	}
+}
+
+func TestSamplingPrunesGroups(t *testing.T) {
+	logger := NewLogger()
+	logger.SetSampling(1, time.Second)
+
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		logger.sampler.allow(samplingKey(fmt.Sprint(i)), start)
+	}
+	if n := len(logger.sampler.groups); n != 100 {
+		t.Fatalf("Expected 100 groups, got %d", n)
+	}
+
+	// Expired groups are dropped once their interval has passed
+	if !logger.sampler.allow("late", start.Add(time.Second)) {
+		t.Error("Expected the first record of a new group to pass")
+	}
+	if n := len(logger.sampler.groups); n != 1 {
+		t.Errorf("Expected expired groups to be pruned, got %d groups", n)
+	}
+}
+
+func TestAuditChain(t *testing.T) {
+	key := []byte("audit-secret")
+
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+	logger.EnableAuditChain(key)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+
+	logger.Info("user created", Field{Key: "user_id", Value: 1})
+	logger.Warn("permission changed", Field{Key: "role", Value: "admin"})
+	logger.Info("user deleted")
+
+	output := buf.String()
+	for _, want := range []string{"audit seq=1 ", "audit seq=2 ", "audit seq=3 "} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %q, got: %s", want, output)
+		}
+	}
+
+	if err := VerifyAuditChain(key, strings.NewReader(output)); err != nil {
+		t.Fatalf("Expected valid chain, got: %v", err)
+	}
+
+	// Altering a record breaks verification
+	altered := strings.Replace(output, `"role": "admin"`, `"role": "user"`, 1)
+	if err := VerifyAuditChain(key, strings.NewReader(altered)); !errors.Is(err, ErrAuditChainBroken) {
+		t.Errorf("Expected altered record to break the chain, got: %v", err)
+	}
+
+	// Removing a record breaks verification
+	var records []string
+	var record strings.Builder
+	for _, line := range strings.SplitAfter(output, "\n") {
+		record.WriteString(line)
+		if strings.HasPrefix(line, "audit seq=") {
+			records = append(records, record.String())
+			record.Reset()
+		}
+	}
+	if len(records) != 3 {
+		t.Fatalf("Expected 3 audited records, got: %d", len(records))
+	}
+	removed := records[0] + records[2]
+	if err := VerifyAuditChain(key, strings.NewReader(removed)); !errors.Is(err, ErrAuditChainBroken) {
+		t.Errorf("Expected removed record to break the chain, got: %v", err)
+	}
+
+	// A different key fails verification
+	if err := VerifyAuditChain([]byte("wrong"), strings.NewReader(output)); !errors.Is(err, ErrAuditChainBroken) {
+		t.Errorf("Expected wrong key to fail verification, got: %v", err)
+	}
+}
+
+func TestAuditChainEscapesMessages(t *testing.T) {
+	key := []byte("audit-secret")
+
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+	logger.EnableAuditChain(key)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+
+	logger.Info("hello\naudit seq=1 hash=00")
+	logger.Info("multi\r\nline", String("note", "a\naudit seq=2 hash=00"))
+
+	if err := VerifyAuditChain(key, strings.NewReader(buf.String())); err != nil {
+		t.Errorf("Expected a valid chain, got: %v\n%s", err, buf.String())
+	}
+	if !strings.Contains(buf.String(), `hello\naudit seq=1 hash=00`) {
+		t.Errorf("Expected the line break to be escaped, got: %q", buf.String())
+	}
+}
+
+func TestAuditChainLevelOutputs(t *testing.T) {
+	key := []byte("audit-secret")
+
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+	logger.EnableAuditChain(key)
+
+	var out, errOut bytes.Buffer
+	logger.SetOutput(&out)
+	logger.SetLevelOutput(LevelError, &errOut)
+
+	logger.Info("first")
+	logger.Error("failed")
+	logger.Info("second")
+	logger.Error("failed again")
+
+	for name, buf := range map[string]*bytes.Buffer{"output": &out, "error output": &errOut} {
+		if err := VerifyAuditChain(key, strings.NewReader(buf.String())); err != nil {
+			t.Errorf("Expected a valid chain on the %s, got: %v\n%s", name, err, buf.String())
+		}
+	}
+}
+
+func TestDefaultLogger(t *testing.T) {
+	original := Default()
+	if original == nil {
+		t.Fatal("Default() returned nil")
+	}
+	defer SetDefault(original)
+
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+	SetDefault(logger)
+
+	if Default() != logger {
+		t.Fatal("SetDefault should replace the default logger")
+	}
+
+	Info("package level info", String("user", "bob"))
+	Errorf("code=%d", 42)
+
+	output := buf.String()
+	for _, want := range []string{"INFO", "package level info", "bob", "ERROR", "code=42"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain '%s', got: %s", want, output)
+		}
+	}
+
+	// The call site is reported, not the package-level wrapper
+	if strings.Contains(output, "default.go") || !strings.Contains(output, "maklogger_test.go") {
+		t.Errorf("Expected caller to be the test file, got: %s", output)
+	}
+
+	SetDefault(nil)
+	if Default() != logger {
+		t.Error("SetDefault(nil) should be ignored")
+	}
+}
+
+func TestGroupField(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+
+	http := Group("http", String("method", "GET"), Int("status", 200), String("path", "/users"))
+	logger.Info("request", http, String("user", "bob"))
+
+	want := `"http": {
+        "method": "GET",
+        "status": 200,
+        "path": "/users"
+      },
+      "user": "bob"`
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("Expected a nested object in order, got: %s", buf.String())
+	}
+
+	buf.Reset()
+	logger.SetFormat(FormatJSON)
+	logger.Info("request", http)
+	if !strings.Contains(buf.String(), `"http":{"method":"GET","status":200,"path":"/users"}`) {
+		t.Errorf("Expected a nested object in JSON output, got: %s", buf.String())
+	}
+}
+
+func TestLazyField(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+	logger.SetLevel(LevelWarn)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+
+	calls := 0
+	field := Lazy("report", func() any {
+		calls++
+		return "expensive"
+	})
+
+	logger.Debug("filtered", field)
+	if calls != 0 {
+		t.Errorf("Expected lazy field not to be evaluated for a filtered record, got %d calls", calls)
+	}
+
+	logger.Warn("emitted", field)
+	if calls != 1 {
+		t.Errorf("Expected lazy field to be evaluated once, got %d calls", calls)
+	}
+	if !strings.Contains(buf.String(), "expensive") {
+		t.Errorf("Expected output to contain lazy value, got: %s", buf.String())
+	}
+}
+
+func TestWriter(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+
+	w := logger.Writer(LevelError)
+	input := []byte("first line\nsecond line\n")
+	n, err := w.Write(input)
+	if err != nil || n != len(input) {
+		t.Fatalf("Write() = %d, %v, want %d, nil", n, err, len(input))
+	}
+
+	output := buf.String()
+	if count := strings.Count(output, "ERROR"); count != 2 {
+		t.Errorf("Expected 2 ERROR records, got %d: %s", count, output)
+	}
+	for _, want := range []string{"first line", "second line"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain '%s', got: %s", want, output)
+		}
+	}
+
+	// Works as the destination of the standard library logger
+	buf.Reset()
+	log.New(logger.Writer(LevelWarn), "", 0).Print("from std log")
+	if !strings.Contains(buf.String(), "WARN") || !strings.Contains(buf.String(), "from std log") {
+		t.Errorf("Expected std log output routed as WARN, got: %s", buf.String())
+	}
+}
+
+func TestSetRedactKeys(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+	logger.SetRedactKeys("password", "Token")
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+
+	fields := []Field{
+		String("password", "hunter2"),
+		String("TOKEN", "secret-token"),
+		String("user", "bob"),
+		{Key: "nested", Value: map[string]string{"password": "visible"}},
+		Group("login", String("password", "group-secret")),
+	}
+
+	for _, format := range []Format{FormatText, FormatJSON, FormatLogfmt} {
+		buf.Reset()
+		logger.SetFormat(format)
+		logger.Info("login", fields...)
+
+		output := buf.String()
+		if strings.Contains(output, "hunter2") || strings.Contains(output, "secret-token") || strings.Contains(output, "group-secret") {
+			t.Errorf("Format %d: redacted value leaked: %s", format, output)
+		}
+		if !strings.Contains(output, redactedValue) || !strings.Contains(output, "bob") {
+			t.Errorf("Format %d: expected redacted and plain values, got: %s", format, output)
+		}
+		// Keys inside map values are not redacted
+		if !strings.Contains(output, "visible") {
+			t.Errorf("Format %d: map values should not be redacted, got: %s", format, output)
+		}
+	}
+
+	logger.SetRedactKeys()
+	buf.Reset()
+	logger.SetFormat(FormatJSON)
+	logger.Info("login", fields...)
+	if !strings.Contains(buf.String(), "hunter2") {
+		t.Errorf("Expected redaction to be disabled, got: %s", buf.String())
+	}
+}
+
+func TestSetLevelOutput(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+
+	var stdout, stderr bytes.Buffer
+	logger.SetOutput(&stdout)
+	logger.SetLevelOutput(LevelError, &stderr)
+
+	logger.Info("regular message")
+	logger.Error("failure message")
+
+	if !strings.Contains(stdout.String(), "regular message") || strings.Contains(stdout.String(), "failure message") {
+		t.Errorf("Unexpected stdout buffer: %s", stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "failure message") || strings.Contains(stderr.String(), "regular message") {
+		t.Errorf("Unexpected stderr buffer: %s", stderr.String())
+	}
+
+	// Removing the override falls back to the default output
+	logger.SetLevelOutput(LevelError, nil)
+	logger.Error("second failure")
+	if !strings.Contains(stdout.String(), "second failure") {
+		t.Errorf("Expected error in default output after removing override, got: %s", stdout.String())
+	}
+}
+
+func TestCloseIdempotent(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+
+	var buf bytes.Buffer
+	logger.SetBufferedOutput(&buf, 4096)
+	logger.Info("pending record")
+
+	for i := 0; i < 2; i++ {
+		if err := logger.Close(); err != nil {
+			t.Fatalf("Close() call %d returned error: %v", i+1, err)
+		}
+	}
+
+	if count := strings.Count(buf.String(), "pending record"); count != 1 {
+		t.Errorf("Expected record to be written once, got %d: %s", count, buf.String())
+	}
+
+	// Close and Flush are no-ops on an unbuffered logger
+	unbuffered := NewLogger()
+	unbuffered.SetOutput(io.Discard)
+	if err := unbuffered.Flush(); err != nil {
+		t.Errorf("Flush() returned error: %v", err)
+	}
+	if err := unbuffered.Close(); err != nil {
+		t.Errorf("Close() returned error: %v", err)
+	}
+	if err := unbuffered.Close(); err != nil {
+		t.Errorf("second Close() returned error: %v", err)
+	}
+}
+
+func TestAsyncPreservesOrder(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+	logger.SetCallerEnabled(false)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+	logger.SetAsync(16, QueueBlock)
+
+	const count = 200
+	for i := 0; i < count; i++ {
+		logger.Infof("record %03d", i)
+	}
+
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Close() returned error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != count {
+		t.Fatalf("Expected %d records after Close, got %d", count, len(lines))
+	}
+	for i, line := range lines {
+		if want := fmt.Sprintf("record %03d", i); !strings.Contains(line, want) {
+			t.Fatalf("Record %d out of order: %s", i, line)
+		}
+	}
+
+	// After Close records are written synchronously
+	logger.Info("after close")
+	if !strings.Contains(buf.String(), "after close") {
+		t.Error("Expected records logged after Close to be written")
+	}
+}
+
+func TestLogRecord(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+
+	record := Record{
+		Time:     time.Date(2025, 9, 2, 10, 30, 45, 123000000, time.Local),
+		Level:    LevelWarn,
+		Message:  "replayed",
+		Fields:   []Field{String("source", "archive")},
+		File:     "/src/app/main.go",
+		Line:     42,
+		Function: "main.run",
+	}
+	logger.Log(record)
+
+	output := buf.String()
+	want := "🕒  2025-09-02 10:30:45.123 │ ⚠️  WARNING  │ 📁 main.go:42 ⚡ run │ 💬  replayed\n"
+	if !strings.HasPrefix(output, want) {
+		t.Errorf("Expected record line %q, got: %q", want, output)
+	}
+	if !strings.Contains(output, `"source": "archive"`) {
+		t.Errorf("Expected record fields, got: %s", output)
+	}
+
+	buf.Reset()
+	logger.SetFormat(FormatJSON)
+	logger.Log(record)
+	for _, want := range []string{`"level":"warning"`, `"msg":"replayed"`, `"caller":"main.go:42"`, `"source":"archive"`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected JSON output to contain %s, got: %s", want, buf.String())
+		}
+	}
+
+	// A zero time is the current time, the level threshold applies
+	buf.Reset()
+	logger.SetClock(func() time.Time { return record.Time })
+	logger.SetLevel(LevelError)
+	logger.Log(Record{Level: LevelWarn, Message: "filtered"})
+	logger.Log(Record{Level: LevelError, Message: "now"})
+	output = buf.String()
+	if strings.Contains(output, "filtered") || !strings.Contains(output, record.Time.Format(time.RFC3339Nano)) {
+		t.Errorf("Expected only the error record at the clock time, got: %s", output)
+	}
+	if strings.Contains(output, `"caller"`) {
+		t.Errorf("Expected no caller for a record without one, got: %s", output)
+	}
+}
+
+// failingWriter fails every write with err.
+type failingWriter struct {
+	err error
+}
+
+func (w failingWriter) Write(p []byte) (int, error) {
+	return 0, w.err
+}
+
+func TestSetWriteErrorHandler(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+
+	broken := failingWriter{err: syscall.EPIPE}
+	var sinkBuf bytes.Buffer
+	logger.SetOutput(broken)
+	logger.AddSink(&sinkBuf, false)
+
+	// Without a handler write errors are ignored
+	logger.Info("ignored")
+
+	var errs []error
+	logger.SetWriteErrorHandler(func(err error) {
+		errs = append(errs, err)
+	})
+	logger.Info("lost")
+
+	if len(errs) != 1 {
+		t.Fatalf("Expected one write error, got: %v", errs)
+	}
+	var writeErr *WriteError
+	if !errors.As(errs[0], &writeErr) || writeErr.Writer != broken || !errors.Is(errs[0], syscall.EPIPE) {
+		t.Errorf("Expected a WriteError for the output wrapping EPIPE, got: %v", errs[0])
+	}
+	if !strings.Contains(sinkBuf.String(), "lost") {
+		t.Errorf("Expected working sinks to receive the record, got: %s", sinkBuf.String())
+	}
+
+	// Failing sinks are reported too
+	errs = nil
+	logger.SetOutput(io.Discard)
+	failingSink := failingWriter{err: io.ErrClosedPipe}
+	logger.AddSink(failingSink, false)
+	logger.Info("sink")
+	if len(errs) != 1 || !errors.As(errs[0], &writeErr) || writeErr.Writer != failingSink {
+		t.Errorf("Expected a WriteError for the sink, got: %v", errs)
+	}
+}
+
+func TestSetFallbackToStderr(t *testing.T) {
+	var fallback bytes.Buffer
+	oldStderr := stderr
+	stderr = &fallback
+	defer func() { stderr = oldStderr }()
+
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+	logger.SetOutput(failingWriter{err: syscall.EPIPE})
+	logger.SetFallbackToStderr(true)
+
+	var errs []error
+	logger.SetWriteErrorHandler(func(err error) {
+		errs = append(errs, err)
+	})
+
+	logger.Info("rescued", String("user", "bob"))
+	if !strings.Contains(fallback.String(), "rescued") || !strings.Contains(fallback.String(), "bob") {
+		t.Errorf("Expected the record on stderr, got: %s", fallback.String())
+	}
+	if len(errs) != 0 {
+		t.Errorf("Expected no write error when the fallback succeeds, got: %v", errs)
+	}
+
+	// The handler is called if stderr fails too
+	stderr = failingWriter{err: io.ErrClosedPipe}
+	logger.Info("lost")
+	if len(errs) != 1 || !errors.Is(errs[0], syscall.EPIPE) || !errors.Is(errs[0], io.ErrClosedPipe) {
+		t.Errorf("Expected a write error wrapping both failures, got: %v", errs)
+	}
+
+	// Without the fallback the handler is called right away
+	errs = nil
+	fallback.Reset()
+	stderr = &fallback
+	logger.SetFallbackToStderr(false)
+	logger.Info("dropped")
+	if fallback.Len() != 0 || len(errs) != 1 {
+		t.Errorf("Expected no fallback and one write error, got: %q, %v", fallback.String(), errs)
+	}
+}
+
+// yieldingWriter is a goroutine-safe buffer that sleeps on every write,
+// making interleaving of concurrent writes likely.
+type yieldingWriter struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (w *yieldingWriter) Write(p []byte) (int, error) {
+	time.Sleep(10 * time.Microsecond)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *yieldingWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func TestLogBatch(t *testing.T) {
+	for _, async := range []bool{false, true} {
+		t.Run(fmt.Sprintf("async=%v", async), func(t *testing.T) {
+			logger := NewLogger()
+			logger.SetColorsEnabled(false)
+			logger.SetCallerEnabled(false)
+
+			var out yieldingWriter
+			logger.SetOutput(&out)
+			if async {
+				logger.SetAsync(8, QueueBlock)
+			}
+
+			const batches, batchSize = 20, 10
+			competing := logger.WithField("source", "competing")
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for i := 0; i < batches*batchSize; i++ {
+					competing.Info("noise")
+				}
+			}()
+
+			for i := 0; i < batches; i++ {
+				records := make([]Record, batchSize)
+				for j := range records {
+					records[j] = Record{Level: LevelInfo, Message: fmt.Sprintf("batch %02d row %02d", i, j)}
+				}
+				logger.LogBatch(records)
+			}
+			<-done
+			if err := logger.Close(); err != nil {
+				t.Fatalf("Close() returned error: %v", err)
+			}
+
+			// Every batch must appear as consecutive lines
+			lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+			rows := 0
+			for i, line := range lines {
+				if !strings.Contains(line, "row 00") {
+					continue
+				}
+				for j := 0; j < batchSize; j++ {
+					if i+j >= len(lines) || !strings.Contains(lines[i+j], fmt.Sprintf("row %02d", j)) {
+						t.Fatalf("Batch interleaved at line %d: %q", i+j, lines[i:min(i+batchSize, len(lines))])
+					}
+					rows++
+				}
+			}
+			if rows != batches*batchSize {
+				t.Errorf("Expected %d batch rows, got %d", batches*batchSize, rows)
+			}
+		})
+	}
+
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+	logger.SetLevel(LevelWarn)
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+	logger.LogBatch([]Record{
+		{Level: LevelDebug, Message: "filtered"},
+		{Level: LevelError, Message: "kept", Fields: []Field{Int("code", 7)}},
+	})
+	output := buf.String()
+	if strings.Contains(output, "filtered") || !strings.Contains(output, "kept") || !strings.Contains(output, `"code": 7`) {
+		t.Errorf("Expected only records above the level threshold, got: %s", output)
+	}
+	if !strings.Contains(output, "maklogger_test.go") {
+		t.Errorf("Expected the caller of LogBatch, got: %s", output)
+	}
+}
+
+func TestCloseContext(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+
+	out := &blockingWriter{started: make(chan struct{}, 1), release: make(chan struct{})}
+	logger.SetOutput(out)
+	logger.SetAsync(1, QueueBlock)
+
+	// The worker blocks on the first record, the second one waits in the queue
+	logger.Info("first")
+	<-out.started
+	logger.Info("second")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := logger.CloseContext(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected a deadline error from CloseContext, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected CloseContext to return promptly, took %v", elapsed)
+	}
+
+	// Once the writer recovers, Close waits for the queue to drain
+	close(out.release)
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Close() returned error: %v", err)
+	}
+	if output := out.buf.String(); !strings.Contains(output, "first") || !strings.Contains(output, "second") {
+		t.Errorf("Expected the queued records to be written after Close, got: %s", output)
+	}
+}
+
+func TestAsyncFlush(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+
+	var buf bytes.Buffer
+	logger.SetBufferedOutput(&buf, 4096)
+	logger.SetAsync(8, QueueBlock)
+	defer logger.Close()
+
+	logger.Info("queued record")
+	if err := logger.Flush(); err != nil {
+		t.Fatalf("Flush() returned error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "queued record") {
+		t.Errorf("Expected Flush to write queued records, got: %s", buf.String())
+	}
+}
+
+// blockingWriter blocks every Write until release is closed.
+type blockingWriter struct {
+	started chan struct{}
+	release chan struct{}
+	buf     bytes.Buffer
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	select {
+	case w.started <- struct{}{}:
+	default:
+	}
+	<-w.release
+	return w.buf.Write(p)
+}
+
+func TestAsyncDropPolicy(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+
+	w := &blockingWriter{started: make(chan struct{}, 1), release: make(chan struct{})}
+	logger.SetOutput(w)
+	logger.SetAsync(1, QueueDrop)
+
+	logger.Info("first")
+	<-w.started // the worker is blocked writing the first record
+	logger.Info("second")
+	logger.Info("third")
+
+	close(w.release)
+	logger.Close()
+
+	output := w.buf.String()
+	if !strings.Contains(output, "first") || !strings.Contains(output, "second") {
+		t.Errorf("Expected queued records to be written, got: %s", output)
+	}
+	if strings.Contains(output, "third") {
+		t.Errorf("Expected record to be dropped when the queue is full, got: %s", output)
+	}
+}
+
+func TestLevelString(t *testing.T) {
+	levels := []Level{LevelInfo, LevelSuccess, LevelDebug, LevelCritical, LevelError, LevelWarn, LevelFatal, LevelPanic}
+	names := []string{"INFO", "SUCCESS", "DEBUG", "CRITICAL", "ERROR", "WARN", "FATAL", "PANIC"}
+
+	for i, level := range levels {
+		if got := level.String(); got != names[i] {
+			t.Errorf("Level(%d).String() = %q, want %q", int(level), got, names[i])
+		}
+
+		// String output parses back to the same level
+		parsed, err := ParseLevel(level.String())
+		if err != nil || parsed != level {
+			t.Errorf("ParseLevel(%q) = %v, %v, want %v", level.String(), parsed, err, level)
+		}
+	}
+
+	if got := Level(99).String(); got != "Level(99)" {
+		t.Errorf("Level(99).String() = %q, want %q", got, "Level(99)")
+	}
+}
+
+func TestParseLevelNames(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Level
+	}{
+		{"debug", LevelDebug},
+		{"INFO", LevelInfo},
+		{"Warn", LevelWarn},
+		{"warning", LevelWarn},
+		{"WARNING", LevelWarn},
+		{" error ", LevelError},
+		{"critical", LevelCritical},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseLevel(tt.input)
+		if err != nil {
+			t.Errorf("ParseLevel(%q) returned error: %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseLevel(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Error("Expected an error for an unknown level")
+	}
+}
+
+func TestSetTheme(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(true)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+
+	theme := DefaultTheme()
+	theme.Levels[LevelInfo] = LevelStyle{
+		Label:      "NOTICE",
+		Icon:       "🏢",
+		Foreground: Color256(15),
+		Background: BgColor256(25),
+		Message:    Cyan,
+	}
+	logger.SetTheme(theme)
+
+	// Later changes to the theme do not affect the logger
+	theme.Levels[LevelInfo] = LevelStyle{Label: "CHANGED"}
+
+	logger.Info("corporate message")
+	logger.Error("default style")
+
+	output := buf.String()
+	for _, want := range []string{
+		"🏢",
+		Colorize("NOTICE  ", Color256(15), BgColor256(25)),
+		Colorize("corporate message", Cyan),
+		Colorize("ERROR   ", BoldWhite, BgRed),
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %q, got: %q", want, output)
+		}
+	}
+	if strings.Contains(output, "CHANGED") || strings.Contains(output, "📝") {
+		t.Errorf("Unexpected style in output: %q", output)
+	}
+
+	if got := logger.Theme().Levels[LevelInfo].Label; got != "NOTICE" {
+		t.Errorf("Theme() info label = %q, want %q", got, "NOTICE")
+	}
+}
+
+func TestSetIconsEnabled(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+
+	if !logger.IconsEnabled() {
+		t.Error("Expected icons to be enabled by default")
+	}
+
+	logger.SetIconsEnabled(false)
+	logger.Info("plain message", String("key", "value"))
+	logger.Success("plain message")
+	logger.Debug("plain message")
+	logger.Critical("plain message")
+	logger.Error("plain message")
+	logger.Warn("plain message")
+
+	output := buf.String()
+	for _, icon := range []string{"🕒", "📁", "⚡", "💬", "📊", "📝", "✅", "🐛", "🛑", "❌", "⚠"} {
+		if strings.Contains(output, icon) {
+			t.Errorf("Expected no %s icon in output, got: %s", icon, output)
+		}
+	}
+	for _, want := range []string{"INFO", "plain message", "Fields:", "maklogger_test.go:"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain '%s', got: %s", want, output)
+		}
+	}
+}
+
+func TestFieldsColor(t *testing.T) {
+	if Gray == BrightBlack {
+		t.Error("Expected Gray to differ from BrightBlack")
+	}
+
+	logger := NewLogger()
+	logger.SetColorsEnabled(true)
+	logger.SetIconsEnabled(false)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+
+	fields := []Field{String("key", "value")}
+	logger.Info("message", fields...)
+
+	// The JSON block is wrapped in the default theme's fields color
+	want := Colorize(logger.formatFieldsAsJSON(fields), Gray) + "\n"
+	if !strings.HasSuffix(buf.String(), want) {
+		t.Errorf("Expected fields block %q, got: %q", want, buf.String())
+	}
+
+	buf.Reset()
+	theme := DefaultTheme()
+	theme.Fields = BrightWhite
+	logger.SetTheme(theme)
+	logger.Info("message", fields...)
+
+	want = Colorize(logger.formatFieldsAsJSON(fields), BrightWhite) + "\n"
+	if !strings.HasSuffix(buf.String(), want) {
+		t.Errorf("Expected themed fields block %q, got: %q", want, buf.String())
+	}
+}
+
+func TestSetFieldColor(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(true)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+	logger.SetFieldColor(Cyan)
+
+	fields := []Field{Int("count", 3)}
+	logger.Info("message", fields...)
+
+	want := string(Cyan) + logger.formatFieldsAsJSON(fields) + string(Reset)
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("Expected cyan fields block %q, got: %q", want, buf.String())
+	}
+	if logger.Theme().Fields != Cyan {
+		t.Errorf("Theme().Fields = %q, want %q", logger.Theme().Fields, Cyan)
+	}
+}
+
+type traceIDKey struct{}
+
+func TestContextExtractor(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+	logger.SetFormat(FormatJSON)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+
+	ctx := context.WithValue(context.Background(), traceIDKey{}, "abc123")
+
+	// Without an extractor the context is ignored
+	logger.InfoContext(ctx, "no extractor")
+	if strings.Contains(buf.String(), "abc123") {
+		t.Errorf("Expected context to be ignored, got: %s", buf.String())
+	}
+
+	calls := 0
+	logger.SetContextExtractor(func(ctx context.Context) []Field {
+		calls++
+		if id, ok := ctx.Value(traceIDKey{}).(string); ok {
+			return []Field{String("trace_id", id)}
+		}
+		return nil
+	})
+
+	buf.Reset()
+	logger.ErrorContext(ctx, "with trace", String("user", "bob"))
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Invalid JSON output %q: %v", buf.String(), err)
+	}
+	if record["trace_id"] != "abc123" || record["user"] != "bob" {
+		t.Errorf("Expected trace_id and user fields, got: %v", record)
+	}
+	if caller, _ := record["caller"].(string); !strings.HasPrefix(caller, "maklogger_test.go:") {
+		t.Errorf("Expected caller to be the test, got: %v", record["caller"])
+	}
+
+	// Filtered records do not run the extractor
+	logger.SetLevel(LevelError)
+	logger.DebugContext(ctx, "filtered")
+	if calls != 1 {
+		t.Errorf("Expected extractor to run once, got %d", calls)
+	}
+
+	// The slog handler passes its context to the extractor
+	buf.Reset()
+	slog.New(NewSlogHandler(logger)).ErrorContext(ctx, "via slog")
+	if !strings.Contains(buf.String(), `"trace_id":"abc123"`) {
+		t.Errorf("Expected slog record to include trace_id, got: %s", buf.String())
+	}
+}
+
+func TestAddHook(t *testing.T) {
+	logger := NewLogger()
+	logger.SetOutput(io.Discard)
+	logger.SetLevel(LevelInfo)
+
+	var levels []Level
+	var order []string
+	logger.AddHook(func(level Level, msg string, fields []Field) {
+		levels = append(levels, level)
+		order = append(order, "first")
+	})
+	logger.AddHook(func(level Level, msg string, fields []Field) {
+		order = append(order, "second")
+	})
+
+	child := logger.WithFields(String("component", "db"))
+	var childFields []Field
+	child.AddHook(func(level Level, msg string, fields []Field) {
+		childFields = fields
+	})
+
+	logger.Info("one")
+	logger.Debug("filtered")
+	logger.Critical("two")
+
+	want := []Level{LevelInfo, LevelCritical}
+	if fmt.Sprint(levels) != fmt.Sprint(want) {
+		t.Errorf("Hook levels = %v, want %v", levels, want)
+	}
+	if got := strings.Join(order, ","); got != "first,second,first,second" {
+		t.Errorf("Hooks ran in order %s", got)
+	}
+	if childFields != nil {
+		t.Error("Hook added to a derived logger should not run for the parent")
+	}
+
+	child.Warn("three")
+	if len(levels) != 3 || len(childFields) != 1 || childFields[0].Key != "component" {
+		t.Errorf("Expected derived logger to run inherited and own hooks, got levels %v, fields %v", levels, childFields)
+	}
+}
+
+func TestHookResolvedFields(t *testing.T) {
+	logger, buf := NewTestLogger()
+	logger.SetRedactKeys("token")
+
+	calls := 0
+	report := Lazy("report", func() any {
+		calls++
+		return "expensive"
+	})
+
+	var hookFields []Field
+	logger.AddHook(func(level Level, msg string, fields []Field) {
+		hookFields = fields
+	})
+	logger.Info("done", report,
+		Group("http", String("method", "GET"), Lazy("status", func() any { return 200 })),
+		Lazy("token", func() any { t.Error("Redacted lazy value computed"); return "secret" }))
+
+	if len(hookFields) != 3 || hookFields[0].Value != "expensive" {
+		t.Fatalf("Expected the hook to see the lazy value, got: %v", hookFields)
+	}
+	group, ok := hookFields[1].Value.([]Field)
+	if !ok || len(group) != 2 || group[1].Value != 200 {
+		t.Errorf("Expected the hook to see the group as resolved []Field, got: %#v", hookFields[1].Value)
+	}
+	if hookFields[2].Value != redactedValue {
+		t.Errorf("Expected the hook to see the redacted placeholder, got: %v", hookFields[2].Value)
+	}
+	if calls != 1 {
+		t.Errorf("Expected lazy field to be evaluated once, got %d calls", calls)
+	}
+	if !strings.Contains(buf.String(), "expensive") || !strings.Contains(buf.String(), `"status": 200`) {
+		t.Errorf("Expected output to contain the lazy values, got: %s", buf.String())
+	}
+}
+
+func TestSetStackTraceLevel(t *testing.T) {
+	logger := NewLogger()
+	logger.SetFormat(FormatJSON)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+	logger.SetStackTraceLevel(LevelError)
+
+	logger.Warn("below threshold")
+	if strings.Contains(buf.String(), `"stack"`) {
+		t.Errorf("Expected no stack below the threshold, got: %s", buf.String())
+	}
+
+	buf.Reset()
+	logger.Error("with stack")
+
+	var record struct {
+		Stack []string `json:"stack"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Invalid JSON output %q: %v", buf.String(), err)
+	}
+	if len(record.Stack) == 0 {
+		t.Fatalf("Expected a stack field, got: %s", buf.String())
+	}
+	// The trace starts at the caller, without internal logger frames
+	if !strings.Contains(record.Stack[0], "TestSetStackTraceLevel") {
+		t.Errorf("Expected stack to start at the test function, got: %v", record.Stack)
+	}
+
+	buf.Reset()
+	logger.DisableStackTrace()
+	logger.Critical("disabled")
+	if strings.Contains(buf.String(), `"stack"`) {
+		t.Errorf("Expected no stack when disabled, got: %s", buf.String())
+	}
+}
+
+func TestDurationField(t *testing.T) {
+	logger := NewLogger()
+	field := Field{Key: "took", Value: 1500 * time.Millisecond}
+
+	result := logger.formatFieldsAsJSON([]Field{field})
+	if !strings.Contains(result, `"took": "1.5s"`) {
+		t.Errorf("Expected duration rendered as 1.5s, got: %s", result)
+	}
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+	logger.SetFormat(FormatJSON)
+	logger.Info("done", field)
+	if !strings.Contains(buf.String(), `"took":"1.5s"`) {
+		t.Errorf("Expected duration rendered as 1.5s in JSON, got: %s", buf.String())
+	}
+
+	logger.SetRawDurations(true)
+	result = logger.formatFieldsAsJSON([]Field{field})
+	if !strings.Contains(result, `"took": 1500000000`) {
+		t.Errorf("Expected raw nanoseconds, got: %s", result)
+	}
+}
+
+func TestTimeField(t *testing.T) {
+	at := time.Date(2025, 9, 2, 15, 30, 45, 0, time.FixedZone("MSK", 3*60*60))
+
+	logger := NewLogger()
+	logger.SetTimeFormat("02.01.2006 15:04")
+
+	result := logger.formatFieldsAsJSON([]Field{{Key: "at", Value: at}})
+	if !strings.Contains(result, `"at": "02.09.2025 15:30"`) {
+		t.Errorf("Expected time field in the logger's layout, got: %s", result)
+	}
+
+	logger.SetUTC(true)
+	result = logger.formatFieldsAsJSON([]Field{{Key: "at", Value: at}})
+	if !strings.Contains(result, `"at": "02.09.2025 12:30"`) {
+		t.Errorf("Expected time field converted to UTC, got: %s", result)
+	}
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+	logger.SetFormat(FormatJSON)
+	logger.Info("event", Field{Key: "at", Value: at})
+	if !strings.Contains(buf.String(), `"at":"2025-09-02T12:30:45Z"`) {
+		t.Errorf("Expected RFC 3339 UTC time in JSON, got: %s", buf.String())
+	}
+}
+
+func TestNamed(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+	logger.SetLevel(LevelInfo)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+
+	pool := logger.Named("db").Named("pool")
+	if pool.Name() != "db.pool" {
+		t.Errorf("Name() = %q, want %q", pool.Name(), "db.pool")
+	}
+
+	pool.Info("connection acquired")
+	pool.Debug("filtered by the parent level")
+
+	output := buf.String()
+	if !strings.Contains(output, "INFO     │ [db.pool] │") {
+		t.Errorf("Expected name segment after the level, got: %s", output)
+	}
+	if strings.Contains(output, "filtered") {
+		t.Errorf("Expected derived logger to keep the parent level, got: %s", output)
+	}
+	if logger.Name() != "" {
+		t.Errorf("Expected parent name to stay empty, got %q", logger.Name())
+	}
+}
+
+func TestSetLevelLabel(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+	logger.SetIconsEnabled(false)
+	logger.SetCallerEnabled(false)
+	logger.SetTimeFormat("")
+
+	levels := []Level{LevelInfo, LevelSuccess, LevelDebug, LevelCritical, LevelError, LevelWarn, LevelFatal, LevelPanic}
+	badgeWidths := func() map[int][]string {
+		widths := make(map[int][]string)
+		for _, level := range levels {
+			badge, _, _ := strings.Cut(logger.Sprint(level, "message"), logger.Separator())
+			widths[utf8.RuneCountInString(badge)] = append(widths[utf8.RuneCountInString(badge)], badge)
+		}
+		return widths
+	}
+
+	if widths := badgeWidths(); len(widths) != 1 || widths[len("CRITICAL")] == nil {
+		t.Errorf("Expected all badges padded to the width of CRITICAL, got: %q", widths)
+	}
+
+	logger.SetLevelLabel(LevelWarn, "WARN")
+	if got := logger.Sprint(LevelWarn, "message"); !strings.HasPrefix(got, "WARN     │") {
+		t.Errorf("Expected the custom label, got: %q", got)
+	}
+
+	// A wider label widens every badge
+	logger.SetLevelLabel(LevelCritical, "CRITICAL!!")
+	if widths := badgeWidths(); len(widths) != 1 || widths[len("CRITICAL!!")] == nil {
+		t.Errorf("Expected all badges padded to the widest label, got: %q", widths)
+	}
+	if style := logger.Theme().Levels[LevelCritical]; style.Label != "CRITICAL!!" || style.Background != BgBrightRed {
+		t.Errorf("Expected only the label to change, got: %+v", style)
 	}
 }
 
-func TestSetColorsEnabled(t *testing.T) {
+func TestSetTypedFieldColors(t *testing.T) {
 	logger := NewLogger()
+	logger.SetColorsEnabled(true)
+	logger.SetTypedFieldColors(true)
 
-	// Test disabling colors
-	logger.SetColorsEnabled(false)
-	if logger.ColorsEnabled() {
-		t.Error("Colors should be disabled")
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+	logger.Info("typed",
+		Int("count", -42),
+		String("name", `say "hi": ok`),
+		Bool("ok", true),
+		Field{Key: "missing", Value: nil},
+		Group("nested", Float64("ratio", 0.5)),
+	)
+
+	output := buf.String()
+	for _, want := range []string{
+		string(fieldKeyColor) + `"count"` + string(Reset) + ": " + string(fieldNumberColor) + "-42" + string(Reset),
+		string(fieldKeyColor) + `"name"` + string(Reset) + ": " + string(fieldStringColor) + `"say \"hi\": ok"` + string(Reset),
+		string(fieldBoolColor) + "true" + string(Reset),
+		string(fieldNullColor) + "null" + string(Reset),
+		string(fieldKeyColor) + `"ratio"` + string(Reset) + ": " + string(fieldNumberColor) + "0.5" + string(Reset),
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %q, got: %q", want, output)
+		}
+	}
+	if strings.Contains(output, string(logger.fieldColor())+"  {") {
+		t.Errorf("Expected the block not to be in the single fields color, got: %q", output)
+	}
+	if got := StripColors(output); !strings.Contains(got, `"count": -42,`) {
+		t.Errorf("Expected the JSON layout to be unchanged, got: %s", got)
 	}
 
-	// Test enabling colors
-	logger.SetColorsEnabled(true)
-	if !logger.ColorsEnabled() {
-		t.Error("Colors should be enabled")
+	// Grouped numbers are colored as numbers, not strings
+	buf.Reset()
+	logger.SetNumberGrouping(true)
+	logger.Info("grouped", Int("count", 1234567), Float64("ratio", -1234.5), String("name", "1,2"))
+	output = buf.String()
+	for _, want := range []string{
+		string(fieldNumberColor) + `"1,234,567"` + string(Reset),
+		string(fieldNumberColor) + `"-1,234.5"` + string(Reset),
+		string(fieldStringColor) + `"1,2"` + string(Reset),
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %q, got: %q", want, output)
+		}
+	}
+	logger.SetNumberGrouping(false)
+
+	// Without colors the fields are plain JSON
+	buf.Reset()
+	logger.SetColorsEnabled(false)
+	logger.Info("plain", Int("count", 1))
+	if strings.Contains(buf.String(), "\033[") {
+		t.Errorf("Expected no color codes with colors disabled, got: %q", buf.String())
 	}
 }
 
-func TestLogLevels(t *testing.T) {
+func TestSetMessageColor(t *testing.T) {
 	logger := NewLogger()
-	logger.SetColorsEnabled(false) // Disable colors for easier testing
+	logger.SetColorsEnabled(true)
 
-	tests := []struct {
-		name     string
-		logFunc  func(string, ...Field)
-		message  string
-		expected string
-	}{
-		{"Info", logger.Info, "test info message", "INFO"},
-		{"Success", logger.Success, "test success message", "SUCCESS"},
-		{"Debug", logger.Debug, "test debug message", "DEBUG"},
-		{"Warn", logger.Warn, "test warn message", "WARNING"},
-		{"Error", logger.Error, "test error message", "ERROR"},
-		{"Critical", logger.Critical, "test critical message", "CRITICAL"},
-	}
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			output := captureOutput(func() {
-				tt.logFunc(tt.message)
-			})
+	child := logger.WithFields()
+	logger.SetMessageColor(LevelError, BrightCyan)
 
-			if !strings.Contains(output, tt.expected) {
-				t.Errorf("Expected output to contain '%s', got: %s", tt.expected, output)
-			}
+	logger.Error("cyan message")
+	output := buf.String()
+	if !strings.Contains(output, Colorize("cyan message", BrightCyan)) {
+		t.Errorf("Expected message in BrightCyan, got: %q", output)
+	}
+	// The level badge keeps its style
+	if !strings.Contains(output, Colorize("ERROR   ", BoldWhite, BgRed)) {
+		t.Errorf("Expected default error badge, got: %q", output)
+	}
 
-			if !strings.Contains(output, tt.message) {
-				t.Errorf("Expected output to contain message '%s', got: %s", tt.message, output)
-			}
-		})
+	buf.Reset()
+	child.Error("default message")
+	if !strings.Contains(buf.String(), Colorize("default message", BrightRed)) {
+		t.Errorf("Expected derived logger to keep the default color, got: %q", buf.String())
 	}
 }
 
-func TestLogWithFields(t *testing.T) {
-	logger := NewLogger()
-	logger.SetColorsEnabled(false) // Disable colors for easier testing
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
 
-	fields := []Field{
-		{Key: "user_id", Value: 123},
-		{Key: "username", Value: "testuser"},
-		{Key: "active", Value: true},
+	sink, err := NewFileSink(path, 512, 2)
+	if err != nil {
+		t.Fatalf("NewFileSink returned error: %v", err)
 	}
 
-	output := captureOutput(func() {
-		logger.Info("test message with fields", fields...)
-	})
+	logger := NewLogger()
+	logger.SetOutput(sink)
+	if logger.ColorsEnabled() {
+		t.Error("Expected colors to be disabled for a file sink")
+	}
 
-	// Check that the main message is present
-	if !strings.Contains(output, "test message with fields") {
-		t.Error("Expected output to contain the main message")
+	for i := 0; i < 50; i++ {
+		logger.Infof("record %02d", i)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Errorf("Second Close returned error: %v", err)
 	}
 
-	// Check that Fields: header is present
-	if !strings.Contains(output, "Fields:") {
-		t.Error("Expected output to contain 'Fields:' header")
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatalf("Expected %s to exist: %v", name, err)
+		}
+		if info.Size() > 512 {
+			t.Errorf("Expected %s to be at most 512 bytes, got %d", name, info.Size())
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("Expected backups beyond maxBackups to be deleted, got: %v", err)
 	}
 
-	// Check that field values are present in JSON format
-	if !strings.Contains(output, "user_id") {
-		t.Error("Expected output to contain 'user_id' field")
+	// The newest record is in the current file
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "record 49") {
+		t.Errorf("Expected the current file to contain the last record, got: %s", data)
 	}
 
-	if !strings.Contains(output, "testuser") {
-		t.Error("Expected output to contain 'testuser' value")
+	if _, err := sink.Write([]byte("after close")); err == nil {
+		t.Error("Expected an error writing to a closed sink")
 	}
 }
 
-func TestFieldTypes(t *testing.T) {
+func TestAddSink(t *testing.T) {
 	logger := NewLogger()
 	logger.SetColorsEnabled(false)
 
-	// Test various field types
-	fields := []Field{
-		{Key: "string_field", Value: "test string"},
-		{Key: "int_field", Value: 42},
-		{Key: "float_field", Value: 3.14},
-		{Key: "bool_field", Value: true},
-		{Key: "nil_field", Value: nil},
-	}
+	var main, colored, plain bytes.Buffer
+	logger.SetOutput(&main)
+	logger.AddSink(&colored, true)
+	logger.AddSink(&plain, false)
 
-	output := captureOutput(func() {
-		logger.Info("testing field types", fields...)
-	})
+	logger.Error("sink message", String("key", "value"))
 
-	// Verify different types are handled correctly
-	expectedValues := []string{"test string", "42", "3.14", "true", "null"}
-	for _, expected := range expectedValues {
-		if !strings.Contains(output, expected) {
-			t.Errorf("Expected output to contain '%s', got: %s", expected, output)
+	for name, buf := range map[string]*bytes.Buffer{"main": &main, "colored": &colored, "plain": &plain} {
+		if !strings.Contains(buf.String(), "sink message") || !strings.Contains(buf.String(), `"key": "value"`) {
+			t.Errorf("Expected %s sink to receive the record, got: %q", name, buf.String())
 		}
 	}
-}
-
-func TestColorize(t *testing.T) {
-	tests := []struct {
-		name     string
-		text     string
-		fg       Color
-		bg       []Color
-		expected string
-	}{
-		{
-			name:     "foreground only",
-			text:     "test",
-			fg:       Red,
-			expected: "\033[31mtest\033[0m",
-		},
-		{
-			name:     "foreground and background",
-			text:     "test",
-			fg:       Red,
-			bg:       []Color{BgBlue},
-			expected: "\033[31m\033[44mtest\033[0m",
-		},
+	if !strings.Contains(colored.String(), "\033[") {
+		t.Errorf("Expected ANSI escapes in the colored sink, got: %q", colored.String())
+	}
+	if strings.Contains(plain.String(), "\033[") || strings.Contains(main.String(), "\033[") {
+		t.Errorf("Expected no ANSI escapes in plain outputs, got: %q and %q", plain.String(), main.String())
 	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := Colorize(tt.text, tt.fg, tt.bg...)
-			if result != tt.expected {
-				t.Errorf("Expected '%s', got '%s'", tt.expected, result)
-			}
-		})
+	// Structured records are the same for every sink
+	main.Reset()
+	colored.Reset()
+	plain.Reset()
+	logger.SetFormat(FormatJSON)
+	logger.Info("json message")
+	if colored.String() != main.String() || plain.String() != main.String() {
+		t.Errorf("Expected identical JSON records, got %q, %q and %q", main.String(), colored.String(), plain.String())
 	}
 }
 
-func TestColorizeIfEnabled(t *testing.T) {
-	text := "test"
-	colored := ColorizeIfEnabled(text, true, Red)
-	uncolored := ColorizeIfEnabled(text, false, Red)
+func TestAddSinkLazyOnce(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
 
-	// When enabled, should return colored text
-	if colored == text {
-		t.Error("Expected colored text when colors are enabled")
-	}
+	var main, colored bytes.Buffer
+	logger.SetOutput(&main)
+	logger.AddSink(&colored, true)
 
-	// When disabled, should return plain text
-	if uncolored != text {
-		t.Error("Expected plain text when colors are disabled")
+	calls := 0
+	logger.Info("lazy", Lazy("n", func() any {
+		calls++
+		return calls
+	}))
+
+	if calls != 1 {
+		t.Errorf("Expected the lazy field to be computed once, got %d calls", calls)
+	}
+	if !strings.Contains(main.String(), `"n": 1`) || !strings.Contains(colored.String(), `"n": 1`) {
+		t.Errorf("Expected the same value in every sink, got %q and %q", main.String(), colored.String())
 	}
 }
 
-func TestFormatFieldsAsJSON(t *testing.T) {
+func TestAddFormatSink(t *testing.T) {
 	logger := NewLogger()
+	logger.SetColorsEnabled(true)
 
-	// Test empty fields
-	result := logger.formatFieldsAsJSON([]Field{})
-	if result != "" {
-		t.Error("Expected empty string for no fields")
+	var text, jsonA, jsonB bytes.Buffer
+	logger.SetOutput(&text)
+	logger.AddFormatSink(&jsonA, FormatJSON, false)
+	logger.AddFormatSink(&jsonB, FormatJSON, true)
+
+	logger.Warn("two formats", String("user", "bob"))
+
+	if !strings.Contains(text.String(), "two formats") || !strings.Contains(text.String(), "\033[") {
+		t.Errorf("Expected a colored text record on the output, got: %q", text.String())
+	}
+	if jsonA.String() != jsonB.String() {
+		t.Errorf("Expected identical JSON records, got %q and %q", jsonA.String(), jsonB.String())
 	}
 
-	// Test single field
-	fields := []Field{
-		{Key: "test_key", Value: "test_value"},
+	var record map[string]any
+	if err := json.Unmarshal(jsonA.Bytes(), &record); err != nil {
+		t.Fatalf("Expected a JSON record, got %q: %v", jsonA.String(), err)
 	}
-	result = logger.formatFieldsAsJSON(fields)
+	if record["msg"] != "two formats" || record["level"] != "warning" || record["user"] != "bob" {
+		t.Errorf("Unexpected JSON record: %v", record)
+	}
+}
 
-	if !strings.Contains(result, "test_key") {
-		t.Error("Expected result to contain field key")
+func TestSetMaxPerSecond(t *testing.T) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+	logger.SetMaxPerSecond(LevelInfo, 10)
+
+	for i := 0; i < 1000; i++ {
+		logger.Info("flood")
 	}
+	logger.Warn("other level")
 
-	if !strings.Contains(result, "test_value") {
-		t.Error("Expected result to contain field value")
+	if count := strings.Count(buf.String(), "flood"); count > 10 || count == 0 {
+		t.Errorf("Expected at most 10 records, got %d", count)
+	}
+	if !strings.Contains(buf.String(), "other level") {
+		t.Error("Expected other levels not to be limited")
 	}
 
-	// Check JSON formatting with proper indentation
-	if !strings.Contains(result, "  {") {
-		t.Error("Expected proper JSON indentation")
+	// The summary is written when the next window starts
+	logger.SetMaxPerSecond(LevelInfo, 10)
+	start := time.Now()
+	buf.Reset()
+	for i := 0; i < 1000; i++ {
+		logger.Log(Record{Time: start, Level: LevelInfo, Message: "flood"})
 	}
-}
+	logger.Log(Record{Time: start.Add(time.Second), Level: LevelInfo, Message: "after window"})
 
-func TestGetCallerInfo(t *testing.T) {
-	file, line, function := getCallerInfo(0)
+	output := buf.String()
+	summary := strings.Index(output, "suppressed 990 messages")
+	if summary < 0 || summary > strings.Index(output, "after window") {
+		t.Errorf("Expected a summary before the next record, got: %s", output)
+	}
 
-	// Should not return default values for valid caller
-	if file == "???" || line == 0 || function == "???" {
-		t.Error("getCallerInfo should return valid caller information")
+	// Summaries are written with a record of another level
+	logger.SetMaxPerSecond(LevelInfo, 10)
+	logger.SetMaxPerSecond(LevelDebug, 1)
+	buf.Reset()
+	for i := 0; i < 15; i++ {
+		logger.Log(Record{Time: start, Level: LevelInfo, Message: "flood"})
+		logger.Log(Record{Time: start, Level: LevelDebug, Message: "flood"})
 	}
+	logger.Log(Record{Time: start.Add(time.Second), Level: LevelWarn, Message: "other level"})
 
-	// File should be the test file
-	if !strings.Contains(file, "test.go") {
-		t.Errorf("Expected file to contain 'test.go', got: %s", file)
+	output = buf.String()
+	if !strings.Contains(output, "suppressed 5 messages") || !strings.Contains(output, "suppressed 14 messages") {
+		t.Errorf("Expected summaries for both levels, got: %s", output)
 	}
 
-	// Function should contain test function name
-	if !strings.Contains(function, "Test") {
-		t.Errorf("Expected function to contain 'Test', got: %s", function)
+	// Pending summaries are written on Flush
+	logger.SetMaxPerSecond(LevelInfo, 10)
+	buf.Reset()
+	for i := 0; i < 12; i++ {
+		logger.Info("flood")
+	}
+	logger.Flush()
+	if !strings.Contains(buf.String(), "suppressed 2 messages") {
+		t.Errorf("Expected a summary on Flush, got: %s", buf.String())
 	}
 
-	// Line should be positive
-	if line <= 0 {
-		t.Errorf("Expected positive line number, got: %d", line)
+	logger.SetMaxPerSecond(LevelInfo, 0)
+	logger.SetMaxPerSecond(LevelDebug, 0)
+	buf.Reset()
+	for i := 0; i < 20; i++ {
+		logger.Info("unlimited")
+	}
+	if count := strings.Count(buf.String(), "unlimited"); count != 20 {
+		t.Errorf("Expected limit to be removed, got %d records", count)
 	}
 }
 
-func TestLogTimestamp(t *testing.T) {
+func TestConditionalLogging(t *testing.T) {
 	logger := NewLogger()
 	logger.SetColorsEnabled(false)
 
-	output := captureOutput(func() {
-		logger.Info("timestamp test")
-	})
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
 
-	// Check that output contains timestamp-like format (YYYY-MM-DD HH:MM:SS.mmm)
-	if !strings.Contains(output, "2025-") && !strings.Contains(output, "2024-") {
-		t.Error("Expected output to contain year")
+	logger.InfoIf(false, "hidden info")
+	logger.ErrorIf(false, "hidden error")
+	if buf.Len() != 0 {
+		t.Errorf("Expected no output for false conditions, got: %s", buf.String())
 	}
 
-	// Check for time separator
-	if !strings.Contains(output, ":") {
-		t.Error("Expected output to contain time separators")
+	logger.WarnIf(true, "shown warning", String("flag", "on"))
+	output := buf.String()
+	for _, want := range []string{"WARN", "shown warning", "on", "maklogger_test.go:"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain '%s', got: %s", want, output)
+		}
 	}
 }
 
-func TestComplexFieldValues(t *testing.T) {
+func TestSprint(t *testing.T) {
 	logger := NewLogger()
-	logger.SetColorsEnabled(false)
+	logger.SetColorsEnabled(true)
+	logger.SetTimeFormat("")
+	logger.SetCallerEnabled(false)
 
-	// Test complex nested structures
-	complexValue := map[string]interface{}{
-		"nested": map[string]interface{}{
-			"key":    "value",
-			"number": 42,
-		},
-		"array": []string{"item1", "item2"},
-	}
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
 
-	fields := []Field{
-		{Key: "complex", Value: complexValue},
+	fields := []Field{String("user", "bob"), Int("attempt", 2)}
+	got := logger.Sprint(LevelInfo, "rendered", fields...)
+	if buf.Len() != 0 {
+		t.Errorf("Expected Sprint not to write, got: %q", buf.String())
 	}
 
-	output := captureOutput(func() {
-		logger.Info("complex field test", fields...)
-	})
+	logger.Info("rendered", fields...)
+	if got != buf.String() {
+		t.Errorf("Sprint() = %q, want %q", got, buf.String())
+	}
 
-	// Should contain nested structure elements
-	if !strings.Contains(output, "nested") {
-		t.Error("Expected output to contain nested structure")
+	// Sampling keys are stripped like in written records
+	if got := logger.Sprint(LevelInfo, "sampled", SamplingKey("group")); strings.Contains(got, "sampling_key") {
+		t.Errorf("Expected Sprint to strip the sampling key, got: %q", got)
 	}
 
-	if !strings.Contains(output, "array") {
-		t.Error("Expected output to contain array field")
+	logger.SetFormat(FormatJSON)
+	if got := logger.Sprint(LevelError, "json"); !strings.Contains(got, `"level":"error"`) {
+		t.Errorf("Expected Sprint to respect the format, got: %q", got)
 	}
 }
 
@@ -309,12 +4192,80 @@ func BenchmarkLogger_Info(b *testing.B) {
 	os.Stdout, _ = os.Open(os.DevNull)
 	defer func() { os.Stdout = old }()
 
+	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		logger.Info("benchmark test message")
 	}
 }
 
+func BenchmarkLogger_InfoCaller(b *testing.B) {
+	for _, enabled := range []bool{true, false} {
+		b.Run(fmt.Sprintf("enabled=%v", enabled), func(b *testing.B) {
+			logger := NewLogger()
+			logger.SetColorsEnabled(false)
+			logger.SetCallerEnabled(enabled)
+			logger.SetOutput(io.Discard)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				logger.Info("benchmark test message")
+			}
+		})
+	}
+}
+
+func BenchmarkLogger_InfoSinks(b *testing.B) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(true)
+	logger.SetOutput(io.Discard)
+	logger.AddSink(io.Discard, true)
+	logger.AddSink(io.Discard, false)
+	logger.AddSink(io.Discard, false)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Info("benchmark test message", Int("i", i))
+	}
+}
+
+// marshalCounter counts how often it is marshaled to JSON.
+type marshalCounter struct {
+	calls *int
+}
+
+func (c marshalCounter) MarshalJSON() ([]byte, error) {
+	*c.calls++
+	return []byte("1"), nil
+}
+
+func BenchmarkLogger_InfoFormatSinks(b *testing.B) {
+	logger := NewLogger()
+	logger.SetColorsEnabled(false)
+	logger.SetOutput(io.Discard)
+	logger.AddFormatSink(io.Discard, FormatJSON, false)
+	logger.AddFormatSink(io.Discard, FormatJSON, true)
+	logger.AddSink(io.Discard, false)
+
+	calls := 0
+	field := Field{Key: "counter", Value: marshalCounter{&calls}}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Info("benchmark test message", field)
+	}
+	b.StopTimer()
+
+	// One text rendering shared by the output and text sink, one JSON rendering
+	// shared by both JSON sinks
+	if calls != 2*b.N {
+		b.Fatalf("Expected 2 marshals per record, got %d for %d records", calls, b.N)
+	}
+}
+
 func BenchmarkLogger_InfoWithFields(b *testing.B) {
 	logger := NewLogger()
 	logger.SetColorsEnabled(false)
@@ -330,6 +4281,7 @@ func BenchmarkLogger_InfoWithFields(b *testing.B) {
 		{Key: "timestamp", Value: "2025-09-02T15:30:45Z"},
 	}
 
+	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		logger.Info("benchmark test with fields", fields...)
@@ -0,0 +1,90 @@
+package maklogger
+
+import "time"
+
+// OTelRecord is a log record in the OpenTelemetry log data model.
+type OTelRecord struct {
+	Timestamp      time.Time
+	SeverityNumber int
+	SeverityText   string
+	Body           string
+	// Attributes holds the fields of the record, with values rendered like in
+	// JSON output, followed by the code.* attributes of the call site, with
+	// the full file path, if caller information is enabled.
+	Attributes []Field
+	// ScopeName is the logger name, used as the instrumentation scope.
+	ScopeName string
+}
+
+// OTelEmitter receives records converted to the OpenTelemetry log data model.
+// Implement it with an adapter around an OTLP exporter, so the core package
+// stays free of OpenTelemetry dependencies.
+type OTelEmitter interface {
+	Emit(record OTelRecord)
+}
+
+// OTelEmitterFunc adapts a function to the OTelEmitter interface.
+type OTelEmitterFunc func(record OTelRecord)
+
+// Emit calls f(record).
+func (f OTelEmitterFunc) Emit(record OTelRecord) {
+	f(record)
+}
+
+// SetOTelEmitter sets an emitter that receives every record written by the
+// logger, converted to the OpenTelemetry log data model. Records are emitted
+// synchronously, also in async mode. Passing nil removes the emitter.
+func (mk *MakLogger) SetOTelEmitter(e OTelEmitter) {
+	mk.otelEmitter = e
+}
+
+// otelSeverity returns the OpenTelemetry severity number of a level.
+// Success is mapped to INFO2, Critical to ERROR2 and Fatal to FATAL2, so the
+// numbers follow the severity order of the levels.
+func otelSeverity(level Level) int {
+	switch level {
+	case LevelDebug:
+		return 5
+	case LevelInfo:
+		return 9
+	case LevelSuccess:
+		return 10
+	case LevelWarn:
+		return 13
+	case LevelError:
+		return 17
+	case LevelCritical:
+		return 18
+	case LevelPanic:
+		return 21
+	case LevelFatal:
+		return 22
+	}
+
+	return 0
+}
+
+// emitOTel converts a record and hands it to the OpenTelemetry emitter.
+func (mk *MakLogger) emitOTel(level Level, now time.Time, file string, line int, fn string, msg string, fields []Field) {
+	entries := mk.fieldEntries(fields, true)
+	attributes := make([]Field, 0, len(entries)+3)
+	for _, entry := range entries {
+		attributes = append(attributes, Field{Key: entry.key, Value: entry.value})
+	}
+	if file != "" {
+		attributes = append(attributes,
+			String("code.filepath", file),
+			Int("code.lineno", line),
+			String("code.function", fn),
+		)
+	}
+
+	mk.otelEmitter.Emit(OTelRecord{
+		Timestamp:      now,
+		SeverityNumber: otelSeverity(level),
+		SeverityText:   level.String(),
+		Body:           msg,
+		Attributes:     attributes,
+		ScopeName:      mk.name,
+	})
+}
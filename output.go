@@ -0,0 +1,306 @@
+package maklogger
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// SetBufferedOutput sets w as the log destination, wrapped in a bufio.Writer
+// of the given size. Buffered records are written to w on Flush or Close.
+// Records still buffered for the previous output are flushed first.
+// Unless colors were set explicitly, they are re-evaluated for w.
+func (mk *MakLogger) SetBufferedOutput(w io.Writer, size int) {
+	mk.Flush()
+	buffered := &bufferedWriter{w: bufio.NewWriterSize(w, size)}
+	mk.out = buffered
+	mk.buffered = buffered
+	mk.detectColors(w)
+}
+
+// bufferedWriter is a bufio.Writer that is safe for concurrent use, so
+// records can be logged while another goroutine calls Flush.
+type bufferedWriter struct {
+	mu sync.Mutex
+	w  *bufio.Writer
+}
+
+// Write writes p to the buffer.
+func (b *bufferedWriter) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.w.Write(p)
+}
+
+// Flush writes the buffered data to the underlying writer.
+func (b *bufferedWriter) Flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.w.Flush()
+}
+
+// SetLevelOutput routes records of the given level to w instead of the
+// default output, e.g. to send errors to stderr. A nil w removes the override.
+func (mk *MakLogger) SetLevelOutput(level Level, w io.Writer) {
+	if w == nil {
+		delete(mk.levelOutputs, level)
+		return
+	}
+	if mk.levelOutputs == nil {
+		mk.levelOutputs = make(map[Level]io.Writer)
+	}
+	mk.levelOutputs[level] = w
+}
+
+// sink is an additional destination of log records.
+type sink struct {
+	w      io.Writer
+	colors bool
+
+	// format is used instead of the logger's format if ownFormat is set
+	format    Format
+	ownFormat bool
+}
+
+// AddSink adds a writer that receives every record in addition to the
+// output, with its own color setting, e.g. a colored console and a plain
+// log file. Each record is formatted at most once per format and color setting.
+// Per-level output routing and the audit chain apply to the output only.
+func (mk *MakLogger) AddSink(w io.Writer, colors bool) {
+	mk.addSink(sink{w: w, colors: colors})
+}
+
+// AddFormatSink adds a writer like AddSink that receives records in the given
+// format regardless of the logger's format, e.g. JSON for a log shipper next to
+// colored text on the console. Colors only apply to FormatText.
+func (mk *MakLogger) AddFormatSink(w io.Writer, format Format, colors bool) {
+	mk.addSink(sink{w: w, colors: colors, format: format, ownFormat: true})
+}
+
+// addSink appends s to the sinks.
+func (mk *MakLogger) addSink(s sink) {
+	mk.sinks = append(mk.sinks, s)
+}
+
+// renderStyle identifies the format and color setting a record is rendered
+// with. Structured formats do not depend on colors, so their colors are unset.
+type renderStyle struct {
+	format Format
+	colors bool
+}
+
+// outputStyle returns the style records are rendered with for the output.
+func (mk *MakLogger) outputStyle() renderStyle {
+	return renderStyle{format: mk.format, colors: mk.colorsEnabled && mk.format == FormatText}
+}
+
+// sinkStyle returns the style records are rendered with for s.
+func (mk *MakLogger) sinkStyle(s sink) renderStyle {
+	format := mk.format
+	if s.ownFormat {
+		format = s.format
+	}
+	return renderStyle{format: format, colors: s.colors && format == FormatText}
+}
+
+// rendering is a record rendered in one style.
+type rendering struct {
+	style renderStyle
+	b     *bytes.Buffer
+}
+
+// lookupRendering returns the buffer rendered in style, or nil.
+func lookupRendering(renderings []rendering, style renderStyle) *bytes.Buffer {
+	for _, r := range renderings {
+		if r.style == style {
+			return r.b
+		}
+	}
+	return nil
+}
+
+// levelOutput returns the writer records of the given level are sent to.
+func (mk *MakLogger) levelOutput(level Level) io.Writer {
+	if out, ok := mk.levelOutputs[level]; ok {
+		return out
+	}
+	return mk.Output()
+}
+
+// SetFlushEach sets whether the output is flushed after every record by
+// calling its Flush or Sync method, if it has one. This trades throughput
+// for durability, e.g. for audit logs written to files.
+func (mk *MakLogger) SetFlushEach(enabled bool) {
+	mk.flushEach = enabled
+}
+
+// syncWriter flushes w if it supports Flush or Sync.
+func syncWriter(w io.Writer) error {
+	switch s := w.(type) {
+	case interface{ Flush() error }:
+		return s.Flush()
+	case interface{ Sync() error }:
+		return s.Sync()
+	}
+	return nil
+}
+
+// Flush writes any buffered log output to the underlying writer.
+// Pending rate limit summaries are written first. In async mode it then waits
+// for queued records to be written.
+func (mk *MakLogger) Flush() error {
+	mk.writeSuppressed(mk.now(), true)
+	if mk.async != nil {
+		return mk.async.flush(mk.flushBuffered)
+	}
+	return mk.flushBuffered()
+}
+
+// flushBuffered flushes the bufio.Writer set by SetBufferedOutput, if any.
+func (mk *MakLogger) flushBuffered() error {
+	if mk.buffered == nil {
+		return nil
+	}
+	return mk.buffered.Flush()
+}
+
+// Close flushes any pending log output. If trailing resets were omitted,
+// a single Reset code is written first to the output and the colored sinks.
+// In async mode the queue is drained and the worker stopped; later records are
+// written synchronously. The underlying writer is not closed, and Close is
+// safe to call more than once, e.g. via defer logger.Close().
+func (mk *MakLogger) Close() error {
+	return mk.CloseContext(context.Background())
+}
+
+// CloseContext is like Close, but in async mode it stops waiting for the queue
+// to drain when ctx is done, e.g. because a writer is stuck, and returns
+// ctx.Err(). The worker then keeps writing the queued records in the background
+// as far as the writer allows; call Close or CloseContext again to wait for it.
+func (mk *MakLogger) CloseContext(ctx context.Context) error {
+	if a := mk.async; a != nil {
+		// Drain in the background so a stuck writer cannot block past ctx
+		closed := make(chan struct{})
+		go func() {
+			mk.writeSuppressed(mk.now(), true)
+			a.close()
+			close(closed)
+		}()
+		select {
+		case <-closed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	} else {
+		mk.writeSuppressed(mk.now(), true)
+	}
+
+	if mk.resetPending.Swap(false) {
+		for _, sink := range mk.sinks {
+			if mk.sinkStyle(sink).colors {
+				io.WriteString(sink.w, string(Reset))
+			}
+		}
+		if mk.outputStyle().colors {
+			if _, err := io.WriteString(mk.Output(), string(Reset)); err != nil {
+				return err
+			}
+		}
+	}
+	return mk.Flush()
+}
+
+// levelWriter is an io.Writer that logs each written line at a fixed level.
+type levelWriter struct {
+	logger *MakLogger
+	level  Level
+}
+
+// Writer returns an io.Writer that logs every line written to it as a separate
+// record at the given level, so libraries that accept an io.Writer can log
+// through the logger:
+//
+//	errorLog := log.New(logger.Writer(maklogger.LevelError), "", 0)
+//
+// Trailing newlines and carriage returns are stripped and empty lines are skipped.
+func (mk *MakLogger) Writer(level Level) io.Writer {
+	return &levelWriter{logger: mk, level: level}
+}
+
+// Write logs each line of p as a separate record.
+func (w *levelWriter) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(string(p), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			continue
+		}
+		w.logger.log(w.level, line)
+	}
+	return len(p), nil
+}
+
+// WriteError is passed to the write error handler when writing a record to
+// the output or a sink fails.
+type WriteError struct {
+	Writer io.Writer
+	Err    error
+}
+
+// Error returns the message of the write error.
+func (e *WriteError) Error() string {
+	return fmt.Sprintf("maklogger: write to %T: %v", e.Writer, e.Err)
+}
+
+// Unwrap returns the error returned by the writer.
+func (e *WriteError) Unwrap() error {
+	return e.Err
+}
+
+// SetWriteErrorHandler registers a handler for failed writes of records to
+// the output or a sink, e.g. a broken pipe. The handler receives a
+// *WriteError naming the failed writer. It runs on the writing goroutine, the
+// async worker in async mode, and must not log through the logger. By default
+// write errors are ignored. Passing nil removes the handler.
+func (mk *MakLogger) SetWriteErrorHandler(fn func(error)) {
+	mk.writeErrorHandler = fn
+}
+
+// stderr receives records the output failed to write if the fallback is
+// enabled. Tests override it.
+var stderr io.Writer = os.Stderr
+
+// SetFallbackToStderr sets whether records the output fails to write are
+// written to os.Stderr instead, so they are not lost. The write error handler
+// is only called if that fails too. Sinks have no fallback.
+func (mk *MakLogger) SetFallbackToStderr(enabled bool) {
+	mk.fallbackToStderr = enabled
+}
+
+// outputFailed handles a failed write of a record to the output, writing it
+// to stderr if the fallback is enabled and reporting the failure otherwise
+// or if the fallback fails as well. It returns whether the fallback wrote
+// the record.
+func (mk *MakLogger) outputFailed(out io.Writer, data []byte, err error) bool {
+	if mk.fallbackToStderr && out != stderr {
+		_, fallbackErr := stderr.Write(data)
+		if fallbackErr == nil {
+			return true
+		}
+		err = errors.Join(err, fallbackErr)
+	}
+	mk.writeFailed(out, err)
+	return false
+}
+
+// writeFailed reports a failed write of a record to w.
+func (mk *MakLogger) writeFailed(w io.Writer, err error) {
+	if mk.writeErrorHandler != nil {
+		mk.writeErrorHandler(&WriteError{Writer: w, Err: err})
+	}
+}
@@ -0,0 +1,66 @@
+package maklogger
+
+import "time"
+
+// Record is a log record. The level methods build a Record and pass it to
+// Log; it can also be built directly, e.g. to replay captured logs.
+type Record struct {
+	Time    time.Time
+	Level   Level
+	Message string
+	Fields  []Field
+
+	// Caller information; an empty File omits it
+	File     string
+	Line     int
+	Function string
+}
+
+// Log renders a record and writes it. Like the level methods, it applies the
+// level threshold, sampling, rate limits and hooks. A zero Time is replaced
+// with the current time. The caller information is taken from the record and
+// omitted if caller information is disabled. Fatal and Panic records are
+// written without exiting or panicking.
+func (mk *MakLogger) Log(r Record) {
+	if !mk.enabled(r.Level) {
+		return
+	}
+	if r.Time.IsZero() {
+		r.Time = mk.now()
+	}
+	if mk.callerDisabled {
+		r.File, r.Line, r.Function = "", 0, ""
+	}
+	mk.write(r)
+}
+
+// LogBatch logs records in order and writes them together, so no record
+// logged concurrently by the logger, or by loggers derived from it with
+// WithFields and Named, ends up between them. Each record is handled like
+// one passed to Log, except that records without caller information report
+// the caller of LogBatch.
+func (mk *MakLogger) LogBatch(records []Record) {
+	var file, fn string
+	var line int
+	if !mk.callerDisabled {
+		file, line, fn = getCallerInfo(1 + mk.callerSkip)
+	}
+
+	// Rendered records are collected and submitted at once
+	var pending []asyncRecord
+	batchLogger := *mk
+	batchLogger.batch = &pending
+	for _, r := range records {
+		if !mk.enabled(r.Level) {
+			continue
+		}
+		if mk.stackTraceEnabled(r.Level) {
+			r.Fields = appendStack(r.Fields, captureStack(1+mk.callerSkip))
+		}
+		if r.File == "" {
+			r.File, r.Line, r.Function = file, line, fn
+		}
+		batchLogger.Log(r)
+	}
+	mk.submit(pending...)
+}
@@ -0,0 +1,244 @@
+package maklogger
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// samplingKey is the value type of fields created by SamplingKey.
+type samplingKey string
+
+// SamplingKey returns a field that assigns a record to a sampling group.
+// Records sharing a sampling key are rate-limited together by SetSampling,
+// regardless of their messages. The field itself is not printed.
+func SamplingKey(key string) Field {
+	return Field{Key: "sampling_key", Value: samplingKey(key)}
+}
+
+// sampler limits the number of records per sampling group and interval.
+type sampler struct {
+	first    int
+	interval time.Duration
+
+	mu     sync.Mutex
+	groups map[samplingKey]*samplingGroup
+	pruned time.Time
+}
+
+// samplingGroup counts the records of one group in the current interval.
+type samplingGroup struct {
+	start time.Time
+	count int
+}
+
+// SetSampling limits records carrying a SamplingKey field to the first n
+// records per key within each interval; the rest are dropped.
+// Records without a sampling key are never sampled.
+// A non-positive n disables sampling.
+func (mk *MakLogger) SetSampling(n int, interval time.Duration) {
+	if n <= 0 {
+		mk.sampler = nil
+		return
+	}
+	mk.sampler = &sampler{
+		first:    n,
+		interval: interval,
+		groups:   make(map[samplingKey]*samplingGroup),
+	}
+}
+
+// clone returns a sampler with the same settings and no groups, or nil if s is nil.
+func (s *sampler) clone() *sampler {
+	if s == nil {
+		return nil
+	}
+	return &sampler{
+		first:    s.first,
+		interval: s.interval,
+		groups:   make(map[samplingKey]*samplingGroup),
+	}
+}
+
+// allow reports whether a record of the given group may be logged at now.
+func (s *sampler) allow(key samplingKey, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Drop groups whose interval has expired, at most once per interval
+	if now.Sub(s.pruned) >= s.interval {
+		for k, group := range s.groups {
+			if now.Sub(group.start) >= s.interval {
+				delete(s.groups, k)
+			}
+		}
+		s.pruned = now
+	}
+
+	group, ok := s.groups[key]
+	if !ok || now.Sub(group.start) >= s.interval {
+		group = &samplingGroup{start: now}
+		s.groups[key] = group
+	}
+
+	group.count++
+	return group.count <= s.first
+}
+
+// sample removes sampling key fields and reports whether the record passes
+// the sampler. The returned fields must be used for rendering.
+func (mk *MakLogger) sample(now time.Time, fields []Field) ([]Field, bool) {
+	kept, key, found := stripSamplingKeys(fields)
+	if !found || mk.sampler == nil {
+		return kept, true
+	}
+	return kept, mk.sampler.allow(key, now)
+}
+
+// stripSamplingKeys returns fields without sampling key fields, together with
+// the last sampling key found, if any.
+func stripSamplingKeys(fields []Field) (kept []Field, key samplingKey, found bool) {
+	for _, field := range fields {
+		if k, ok := field.Value.(samplingKey); ok {
+			key, found = k, true
+		}
+	}
+
+	if !found {
+		return fields, "", false
+	}
+
+	kept = make([]Field, 0, len(fields)-1)
+	for _, field := range fields {
+		if _, ok := field.Value.(samplingKey); !ok {
+			kept = append(kept, field)
+		}
+	}
+	return kept, key, true
+}
+
+// rateLimiter limits the number of records per level and second.
+type rateLimiter struct {
+	mu      sync.Mutex
+	windows map[Level]*rateWindow
+}
+
+// rateWindow counts the records of one level in the current second.
+type rateWindow struct {
+	limit      int
+	start      time.Time
+	count      int
+	suppressed int
+}
+
+// SetMaxPerSecond limits records at the given level to n per second. Records
+// over the limit are dropped, and a single "suppressed N messages" record is
+// written at that level with the first record of any level after the second
+// ends, or on Flush or Close, whichever comes first.
+// A non-positive n removes the limit for the level.
+func (mk *MakLogger) SetMaxPerSecond(level Level, n int) {
+	windows := make(map[Level]*rateWindow)
+	if mk.limiter != nil {
+		mk.limiter.mu.Lock()
+		for l, w := range mk.limiter.windows {
+			copied := *w
+			windows[l] = &copied
+		}
+		mk.limiter.mu.Unlock()
+	}
+
+	if n <= 0 {
+		delete(windows, level)
+	} else {
+		windows[level] = &rateWindow{limit: n}
+	}
+
+	if len(windows) == 0 {
+		mk.limiter = nil
+		return
+	}
+	mk.limiter = &rateLimiter{windows: windows}
+}
+
+// clone returns a rate limiter with the same limits and fresh windows, or nil
+// if r is nil.
+func (r *rateLimiter) clone() *rateLimiter {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	windows := make(map[Level]*rateWindow, len(r.windows))
+	for level, w := range r.windows {
+		windows[level] = &rateWindow{limit: w.limit}
+	}
+	return &rateLimiter{windows: windows}
+}
+
+// allow reports whether a record at the given level may be logged at now.
+func (r *rateLimiter) allow(level Level, now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	w, ok := r.windows[level]
+	if !ok {
+		return true
+	}
+
+	if now.Sub(w.start) >= time.Second {
+		w.start = now
+		w.count = 0
+	}
+
+	w.count++
+	if w.count > w.limit {
+		w.suppressed++
+		return false
+	}
+	return true
+}
+
+// rateSummary is the number of records of a level dropped by the rate limiter.
+type rateSummary struct {
+	level      Level
+	suppressed int
+}
+
+// pending returns and clears the suppressed counts of the levels whose window
+// ended before now, or of all levels if all is set, ordered by level.
+func (r *rateLimiter) pending(now time.Time, all bool) []rateSummary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var summaries []rateSummary
+	for level, w := range r.windows {
+		if w.suppressed > 0 && (all || now.Sub(w.start) >= time.Second) {
+			summaries = append(summaries, rateSummary{level: level, suppressed: w.suppressed})
+			w.suppressed = 0
+		}
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].level < summaries[j].level })
+	return summaries
+}
+
+// writeSuppressed writes a "suppressed N messages" record for every level with
+// records dropped in a window that ended before now, or in any window if all
+// is set.
+func (mk *MakLogger) writeSuppressed(now time.Time, all bool) {
+	if mk.limiter == nil {
+		return
+	}
+	summaries := mk.limiter.pending(now, all)
+	if len(summaries) == 0 {
+		return
+	}
+
+	// Summaries are not logged by the caller and not counted by Counts
+	summaryLogger := *mk
+	summaryLogger.counts = nil
+	for _, summary := range summaries {
+		summaryLogger.output(summary.level, now, "", 0, "", fmt.Sprintf("suppressed %d messages", summary.suppressed), nil)
+	}
+}
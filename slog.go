@@ -0,0 +1,138 @@
+package maklogger
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+)
+
+// slogHandler implements slog.Handler on top of a MakLogger.
+type slogHandler struct {
+	logger *MakLogger
+	fields []Field
+	group  string
+}
+
+// NewSlogHandler returns a slog.Handler that writes records through the given
+// logger, so it can be used as the backend of log/slog:
+//
+//	slog.SetDefault(slog.New(maklogger.NewSlogHandler(logger)))
+//
+// Attributes are converted into fields, with group names flattened into
+// dotted keys such as "http.method".
+func NewSlogHandler(logger *MakLogger) slog.Handler {
+	return &slogHandler{logger: logger}
+}
+
+// fromSlogLevel maps a slog level to the closest maklogger level.
+// Levels above slog.LevelError map to LevelCritical.
+func fromSlogLevel(level slog.Level) Level {
+	switch {
+	case level < slog.LevelInfo:
+		return LevelDebug
+	case level < slog.LevelWarn:
+		return LevelInfo
+	case level < slog.LevelError:
+		return LevelWarn
+	case level == slog.LevelError:
+		return LevelError
+	}
+
+	return LevelCritical
+}
+
+// Enabled reports whether the logger accepts records at the given level.
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.logger.enabled(fromSlogLevel(level))
+}
+
+// Handle converts a slog record into fields and writes it through the logger,
+// adding the fields of the logger's context extractor, if any.
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	fields := make([]Field, 0, len(h.fields)+r.NumAttrs())
+	fields = append(fields, h.fields...)
+	r.Attrs(func(attr slog.Attr) bool {
+		fields = appendAttr(fields, h.group, attr)
+		return true
+	})
+	fields = h.logger.contextFields(ctx, fields)
+
+	// An empty file omits the caller when it is disabled
+	var file, fn string
+	var line int
+	switch {
+	case h.logger.callerDisabled:
+	case r.PC == 0:
+		file, fn = "???", "???"
+	default:
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		file, line, fn = frame.File, frame.Line, frame.Function
+	}
+
+	now := r.Time
+	if now.IsZero() {
+		now = h.logger.now()
+	}
+
+	h.logger.write(Record{
+		Time:     now,
+		Level:    fromSlogLevel(r.Level),
+		Message:  r.Message,
+		Fields:   fields,
+		File:     file,
+		Line:     line,
+		Function: fn,
+	})
+	return nil
+}
+
+// WithAttrs returns a handler that includes the given attributes in every record.
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	child := *h
+	child.fields = make([]Field, 0, len(h.fields)+len(attrs))
+	child.fields = append(child.fields, h.fields...)
+	for _, attr := range attrs {
+		child.fields = appendAttr(child.fields, h.group, attr)
+	}
+	return &child
+}
+
+// WithGroup returns a handler that prefixes the keys of subsequent attributes
+// with the group name.
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	child := *h
+	child.group = joinKey(h.group, name)
+	return &child
+}
+
+// appendAttr converts an attribute into fields, flattening groups into dotted keys.
+func appendAttr(fields []Field, group string, attr slog.Attr) []Field {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return fields
+	}
+
+	if attr.Value.Kind() == slog.KindGroup {
+		prefix := joinKey(group, attr.Key)
+		for _, member := range attr.Value.Group() {
+			fields = appendAttr(fields, prefix, member)
+		}
+		return fields
+	}
+
+	return append(fields, Field{Key: joinKey(group, attr.Key), Value: attr.Value.Any()})
+}
+
+// joinKey joins a group prefix and a key with a dot.
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	if key == "" {
+		return prefix
+	}
+	return prefix + "." + key
+}
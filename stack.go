@@ -0,0 +1,52 @@
+package maklogger
+
+import (
+	"runtime"
+	"strconv"
+)
+
+// maxStackDepth is the maximum number of frames captured in a stack trace.
+const maxStackDepth = 64
+
+// SetStackTraceLevel enables stack traces for records at or above the given
+// severity. The stack of the logging call is added as a "stack" field with
+// one "function file:line" entry per frame, starting at the caller.
+// Stack traces are disabled by default.
+func (mk *MakLogger) SetStackTraceLevel(level Level) {
+	mk.stackTrace = true
+	mk.stackTraceLevel = level
+}
+
+// DisableStackTrace disables the stack traces enabled by SetStackTraceLevel.
+func (mk *MakLogger) DisableStackTrace() {
+	mk.stackTrace = false
+}
+
+// stackTraceEnabled reports whether records at the given level include a stack trace.
+func (mk *MakLogger) stackTraceEnabled(level Level) bool {
+	return mk.stackTrace && severity(level) >= severity(mk.stackTraceLevel)
+}
+
+// captureStack returns the stack starting at the specified skip level, where
+// 0 identifies the caller of captureStack, as in getCallerInfo.
+func captureStack(skip int) []string {
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(skip+2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	stack := make([]string, 0, n)
+	for {
+		frame, more := frames.Next()
+		stack = append(stack, frame.Function+" "+frame.File+":"+strconv.Itoa(frame.Line))
+		if !more {
+			break
+		}
+	}
+	return stack
+}
+
+// appendStack returns fields with a "stack" field added, without modifying
+// the backing array of the caller's slice.
+func appendStack(fields []Field, stack []string) []Field {
+	return append(fields[:len(fields):len(fields)], Field{Key: "stack", Value: stack})
+}
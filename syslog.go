@@ -0,0 +1,103 @@
+package maklogger
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// syslogFacility is the facility of syslog records: user-level messages.
+const syslogFacility = 1
+
+// syslogTimeFormat is the RFC 5424 timestamp layout, with microseconds.
+const syslogTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+// syslogHostname, syslogProcess and syslogPID identify the process in syslog records.
+var (
+	syslogHostname = syslogToken(hostname())
+	syslogProcess  = syslogToken(filepath.Base(os.Args[0]))
+	syslogPID      = strconv.Itoa(os.Getpid())
+)
+
+// hostname returns the host name, or an empty string if it is unknown.
+func hostname() string {
+	name, err := os.Hostname()
+	if err != nil {
+		return ""
+	}
+	return name
+}
+
+// syslogSeverity returns the RFC 5424 severity a level is mapped to in
+// FormatSyslog output: Fatal is emergency (0), Panic alert (1),
+// Critical critical (2), Error error (3), Warn warning (4),
+// Success notice (5), Info informational (6) and Debug debug (7).
+func syslogSeverity(level Level) int {
+	switch level {
+	case LevelFatal:
+		return 0
+	case LevelPanic:
+		return 1
+	case LevelCritical:
+		return 2
+	case LevelError:
+		return 3
+	case LevelWarn:
+		return 4
+	case LevelSuccess:
+		return 5
+	case LevelDebug:
+		return 7
+	}
+
+	return 6
+}
+
+// syslogToken returns s as a header field of a syslog record: the nil value
+// "-" if it is empty, with spaces replaced by underscores otherwise.
+func syslogToken(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return strings.ReplaceAll(s, " ", "_")
+}
+
+// renderSyslog formats a record as an RFC 5424 line with the user facility.
+// The app name is the logger name, or the program name for unnamed loggers.
+// The message is followed by the caller and fields as logfmt pairs.
+func (mk *MakLogger) renderSyslog(b *bytes.Buffer, now time.Time, level Level, file string, line int, msg string, fields []Field) {
+	appName := syslogProcess
+	if mk.name != "" {
+		appName = syslogToken(mk.name)
+	}
+
+	b.WriteByte('<')
+	b.WriteString(strconv.Itoa(syslogFacility*8 + syslogSeverity(level)))
+	b.WriteString(">1 ")
+	b.WriteString(now.Format(syslogTimeFormat))
+	b.WriteByte(' ')
+	b.WriteString(syslogHostname)
+	b.WriteByte(' ')
+	b.WriteString(appName)
+	b.WriteByte(' ')
+	b.WriteString(syslogPID)
+	b.WriteString(" - - ")
+	b.WriteString(lineBreakEscaper.Replace(msg))
+
+	if file != "" {
+		b.WriteString(" caller=")
+		b.WriteString(logfmtValue(mk.callerFile(file) + ":" + strconv.Itoa(line)))
+	}
+	timer := mk.fieldsTimer(fields)
+	for _, entry := range mk.fieldEntries(fields, true) {
+		b.WriteByte(' ')
+		b.WriteString(entry.key)
+		b.WriteByte('=')
+		b.WriteString(logfmtValue(entry.value))
+	}
+	mk.checkSlowFields(timer, len(fields))
+	b.WriteString(mk.lineEnding)
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+
+package maklogger
+
+import "os"
+
+// enableWindowsANSI does nothing on non-Windows systems,
+// where ANSI colors are supported by default.
+func (mk *MakLogger) enableWindowsANSI() {}
+
+// isTerminal reports whether f is a character device such as a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,54 @@
+//go:build windows
+
+package maklogger
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// enableWindowsANSI enables ANSI escape sequence support in Windows CMD.
+func (mk *MakLogger) enableWindowsANSI() {
+	defer func() {
+		if r := recover(); r != nil {
+			// If we couldn't enable ANSI, disable colors
+			mk.colorsEnabled = false
+		}
+	}()
+
+	// Windows-specific constants
+	const (
+		STD_OUTPUT_HANDLE                  = ^uintptr(10) // -11 as uintptr
+		ENABLE_VIRTUAL_TERMINAL_PROCESSING = 0x0004
+	)
+
+	// Load Windows API functions
+	kernel32 := syscall.NewLazyDLL("kernel32.dll")
+	procGetConsoleMode := kernel32.NewProc("GetConsoleMode")
+	procSetConsoleMode := kernel32.NewProc("SetConsoleMode")
+	procGetStdHandle := kernel32.NewProc("GetStdHandle")
+
+	handle, _, _ := procGetStdHandle.Call(STD_OUTPUT_HANDLE)
+	var mode uint32
+
+	// Get current console mode
+	ret, _, _ := procGetConsoleMode.Call(handle, uintptr(unsafe.Pointer(&mode)))
+	if ret == 0 {
+		mk.colorsEnabled = false
+		return
+	}
+
+	// Enable virtual terminal
+	mode |= ENABLE_VIRTUAL_TERMINAL_PROCESSING
+	ret, _, _ = procSetConsoleMode.Call(handle, uintptr(mode))
+	if ret == 0 {
+		mk.colorsEnabled = false
+	}
+}
+
+// isTerminal reports whether f is a console.
+func isTerminal(f *os.File) bool {
+	var mode uint32
+	return syscall.GetConsoleMode(syscall.Handle(f.Fd()), &mode) == nil
+}
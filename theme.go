@@ -0,0 +1,242 @@
+package maklogger
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// LevelStyle describes how the level badge and message of a level are rendered
+// in text output. An empty background leaves the terminal background unchanged.
+type LevelStyle struct {
+	Label string
+	Icon  string
+
+	IconColor  Color
+	Foreground Color
+	Background Color
+
+	Message           Color
+	MessageBackground Color
+}
+
+// Theme maps levels to their text output style and sets the color of the
+// JSON fields block. Levels missing from Levels and an empty Fields color
+// use the default theme.
+type Theme struct {
+	Levels map[Level]LevelStyle
+	Fields Color
+}
+
+// DefaultTheme returns the built-in theme.
+func DefaultTheme() Theme {
+	return Theme{
+		Levels: map[Level]LevelStyle{
+			LevelInfo:     {Label: "INFO", Icon: "📝", IconColor: BrightBlue, Foreground: BoldWhite, Background: BgBlue, Message: BrightWhite},
+			LevelSuccess:  {Label: "SUCCESS", Icon: "✅", IconColor: BrightGreen, Foreground: BoldWhite, Background: BgGreen, Message: BrightGreen},
+			LevelDebug:    {Label: "DEBUG", Icon: "🐛", IconColor: BrightMagenta, Foreground: BoldWhite, Background: BgMagenta, Message: BrightMagenta},
+			LevelCritical: {Label: "CRITICAL", Icon: "🛑", IconColor: BrightRed, Foreground: BoldWhite, Background: BgBrightRed, Message: BrightRed, MessageBackground: BgBlack},
+			LevelError:    {Label: "ERROR", Icon: "❌", IconColor: BrightRed, Foreground: BoldWhite, Background: BgRed, Message: BrightRed},
+			LevelWarn:     {Label: "WARNING", Icon: "⚠️", IconColor: BrightYellow, Foreground: Bold, Background: BgYellow, Message: BrightYellow},
+			LevelFatal:    {Label: "FATAL", Icon: "💀", IconColor: BrightRed, Foreground: BoldWhite, Background: BgBrightRed, Message: BrightRed, MessageBackground: BgBlack},
+			LevelPanic:    {Label: "PANIC", Icon: "🔥", IconColor: BrightRed, Foreground: BoldWhite, Background: BgBrightRed, Message: BrightRed, MessageBackground: BgBlack},
+		},
+		Fields: Gray,
+	}
+}
+
+// defaultTheme is used for levels missing from the logger's theme.
+var defaultTheme = DefaultTheme()
+
+// SetTheme sets the colors, icons and labels used for levels in text output.
+func (mk *MakLogger) SetTheme(theme Theme) {
+	// Copy the map so later changes by the caller do not affect the logger
+	levels := make(map[Level]LevelStyle, len(theme.Levels))
+	for level, style := range theme.Levels {
+		levels[level] = style
+	}
+	mk.theme = Theme{Levels: levels, Fields: theme.Fields}
+	mk.updateLabelWidth()
+}
+
+// SetFieldColor sets the color of the JSON fields block in text output,
+// overriding the theme's Fields color.
+func (mk *MakLogger) SetFieldColor(color Color) {
+	mk.theme.Fields = color
+}
+
+// SetTypedFieldColors sets whether fields in text output are colored by type
+// when colors are enabled: keys in blue, strings in green, numbers in cyan,
+// booleans in yellow and null in gray, instead of in the single fields color.
+// With SetNumberGrouping, numbers are rendered as strings; those strings and
+// string values that look like them, e.g. "2024", are colored as numbers.
+func (mk *MakLogger) SetTypedFieldColors(enabled bool) {
+	mk.typedFieldColors = enabled
+}
+
+// SetMessageColor overrides the message text color of a level in text
+// output, keeping the rest of its style. The message background is cleared.
+func (mk *MakLogger) SetMessageColor(level Level, color Color) {
+	style, _ := mk.levelStyle(level)
+	style.Message = color
+	style.MessageBackground = ""
+	mk.setLevelStyle(level, style)
+}
+
+// SetLevelLabel overrides the label of a level in text output, keeping the
+// rest of its style. Labels are padded to the width of the widest one.
+func (mk *MakLogger) SetLevelLabel(level Level, label string) {
+	style, _ := mk.levelStyle(level)
+	style.Label = label
+	mk.setLevelStyle(level, style)
+	mk.updateLabelWidth()
+}
+
+// setLevelStyle sets the style of a level in the logger's theme.
+func (mk *MakLogger) setLevelStyle(level Level, style LevelStyle) {
+	if mk.theme.Levels == nil {
+		mk.theme.Levels = make(map[Level]LevelStyle)
+	}
+	mk.theme.Levels[level] = style
+}
+
+// updateLabelWidth sets the width level labels are padded to, so messages
+// line up, to the width of the widest label.
+func (mk *MakLogger) updateLabelWidth() {
+	mk.labelWidth = 0
+	for _, style := range mk.Theme().Levels {
+		mk.labelWidth = max(mk.labelWidth, utf8.RuneCountInString(style.Label))
+	}
+}
+
+// Theme returns a copy of the logger's theme.
+func (mk *MakLogger) Theme() Theme {
+	theme := DefaultTheme()
+	for level, style := range mk.theme.Levels {
+		theme.Levels[level] = style
+	}
+	if mk.theme.Fields != "" {
+		theme.Fields = mk.theme.Fields
+	}
+	return theme
+}
+
+// levelStyle returns the style of a level, falling back to the default theme.
+func (mk *MakLogger) levelStyle(level Level) (LevelStyle, bool) {
+	if style, ok := mk.theme.Levels[level]; ok {
+		return style, true
+	}
+	style, ok := defaultTheme.Levels[level]
+	return style, ok
+}
+
+// fieldColor returns the color of the JSON fields block.
+func (mk *MakLogger) fieldColor() Color {
+	if mk.theme.Fields != "" {
+		return mk.theme.Fields
+	}
+	return defaultTheme.Fields
+}
+
+// colorizeStyle colors text with fg and an optional background if colors are enabled.
+func (mk *MakLogger) colorizeStyle(text string, fg, bg Color) string {
+	if bg == "" {
+		return ColorizeIfEnabled(text, mk.colorsEnabled, fg)
+	}
+	return ColorizeIfEnabled(text, mk.colorsEnabled, fg, bg)
+}
+
+// getColoredLevel returns a formatted log level with color settings.
+func (mk *MakLogger) getColoredLevel(level Level) string {
+	style, ok := mk.levelStyle(level)
+	if !ok {
+		return "UNDEFINED"
+	}
+
+	label := style.Label
+	if n := utf8.RuneCountInString(label); n < mk.labelWidth {
+		label += strings.Repeat(" ", mk.labelWidth-n)
+	}
+
+	label = mk.colorizeStyle(label, style.Foreground, style.Background)
+	if mk.iconsDisabled {
+		return label
+	}
+	return mk.colorizeStyle(style.Icon+" ", style.IconColor, "") + " " + label
+}
+
+// getColoredMessage returns a formatted message with color settings.
+func (mk *MakLogger) getColoredMessage(level Level, message string) string {
+	style, ok := mk.levelStyle(level)
+	if !ok {
+		return "UNDEFINED"
+	}
+
+	return mk.colorizeStyle(message, style.Message, style.MessageBackground)
+}
+
+// Colors of fields in text output with typed field colors.
+const (
+	fieldKeyColor    = BrightBlue
+	fieldStringColor = Green
+	fieldNumberColor = Cyan
+	fieldBoolColor   = Yellow
+	fieldNullColor   = Gray
+)
+
+// groupedNumber matches the strings numbers are rendered as with number
+// grouping, e.g. "-1,234.5".
+var groupedNumber = regexp.MustCompile(`^-?[0-9]{1,3}(,[0-9]{3})*(\.[0-9]+)?$`)
+
+// writeTypedJSON writes JSON to b, coloring keys and values by type.
+// Punctuation, whitespace and unexpected bytes are written as-is. If grouped
+// is set, strings that look like grouped numbers are colored as numbers.
+func writeTypedJSON(b *bytes.Buffer, data []byte, grouped bool) {
+	for i := 0; i < len(data); {
+		end, color := i+1, Color("")
+		switch c := data[i]; {
+		case c == '"':
+			end = jsonStringEnd(data, i)
+			color = fieldStringColor
+			if rest := bytes.TrimLeft(data[end:], " \t\r\n"); len(rest) > 0 && rest[0] == ':' {
+				color = fieldKeyColor
+			} else if grouped && end-i > 2 && groupedNumber.Match(data[i+1:end-1]) {
+				color = fieldNumberColor
+			}
+		case c == '-' || c >= '0' && c <= '9':
+			for end < len(data) && strings.IndexByte("0123456789.eE+-", data[end]) >= 0 {
+				end++
+			}
+			color = fieldNumberColor
+		case bytes.HasPrefix(data[i:], []byte("true")):
+			end, color = i+len("true"), fieldBoolColor
+		case bytes.HasPrefix(data[i:], []byte("false")):
+			end, color = i+len("false"), fieldBoolColor
+		case bytes.HasPrefix(data[i:], []byte("null")):
+			end, color = i+len("null"), fieldNullColor
+		}
+
+		if color == "" {
+			b.WriteByte(data[i])
+		} else {
+			b.WriteString(string(color))
+			b.Write(data[i:end])
+			b.WriteString(string(Reset))
+		}
+		i = end
+	}
+}
+
+// jsonStringEnd returns the index after the JSON string starting at data[start].
+func jsonStringEnd(data []byte, start int) int {
+	for i := start + 1; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return len(data)
+}
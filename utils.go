@@ -3,13 +3,15 @@ package maklogger
 import (
 	"path/filepath"
 	"runtime"
+	"strings"
 )
 
-// getCallerInfo retrieves the file name, line number, and function name
-// of the caller at the specified skip level in the call stack.
+// getCallerInfo retrieves the file path, line number, and function name
+// of the caller at the specified skip level in the call stack, where 0
+// identifies the caller of getCallerInfo.
 // This is used internally to provide source location information in logs.
 func getCallerInfo(skip int) (file string, line int, function string) {
-	pc, file, line, ok := runtime.Caller(skip)
+	pc, file, line, ok := runtime.Caller(skip + 1)
 	if !ok {
 		return "???", 0, "???"
 	}
@@ -18,5 +20,17 @@ func getCallerInfo(skip int) (file string, line int, function string) {
 	if fn != nil {
 		funcName = fn.Name()
 	}
-	return filepath.Base(file), line, funcName
+	return file, line, funcName
+}
+
+// callerFile returns the file of a call site as shown in records: the base
+// name by default, or the full path without the configured trim prefix.
+func (mk *MakLogger) callerFile(file string) string {
+	if !mk.callerFullPath {
+		return filepath.Base(file)
+	}
+	if mk.callerTrimPrefix != "" && strings.HasPrefix(file, mk.callerTrimPrefix) {
+		return strings.TrimLeft(file[len(mk.callerTrimPrefix):], "/")
+	}
+	return file
 }